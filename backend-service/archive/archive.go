@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	ErrTooManyMembers = errors.New("archive has too many members")
+	ErrTooLarge       = errors.New("archive expands beyond the size limit")
+)
+
+// Member is a log file inside an uploaded archive
+type Member struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Limits guard against archives expanding into too many or too large files
+type Limits struct {
+	MaxMembers          int
+	MaxSize             int64
+	MaxCompressionRatio int64
+}
+
+// Extension returns the archive extension of a file name, keeping the double
+// extension of tarballs, or an empty string if the file is no archive
+func Extension(fileName string) string {
+	name := strings.ToLower(fileName)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(name, ext) {
+			return fileName[len(fileName)-len(ext):]
+		}
+	}
+	return ""
+}
+
+// Inspect lists the log files in a zip or gzipped tar archive. It fails if a
+// member path escapes the archive or the archive exceeds one of the limits.
+func Inspect(filePath string, limits Limits) ([]Member, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Expanding to more than the compression ratio allows is treated as a bomb
+	budget := limits.MaxSize
+	if limits.MaxCompressionRatio > 0 {
+		budget = min(budget, info.Size()*limits.MaxCompressionRatio)
+	}
+
+	if strings.EqualFold(Extension(filePath), ".zip") {
+		return inspectZip(file, info.Size(), budget, limits.MaxMembers)
+	}
+	return inspectTar(file, budget, limits.MaxMembers)
+}
+
+func inspectZip(file *os.File, size, budget int64, maxMembers int) ([]Member, error) {
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+
+	members := make([]Member, 0)
+	total := int64(0)
+	for _, f := range reader.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		member, ok, err := newMember(f.Name, int64(f.UncompressedSize64))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if len(members) >= maxMembers {
+			return nil, ErrTooManyMembers
+		}
+		if total += member.Size; total > budget || member.Size < 0 {
+			return nil, ErrTooLarge
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func inspectTar(file *os.File, budget int64, maxMembers int) ([]Member, error) {
+	decompressor, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip stream: %v", err)
+	}
+	defer decompressor.Close()
+
+	// Listing a tarball reads it completely, so the budget covers the whole stream
+	limited := &io.LimitedReader{R: decompressor, N: budget + 1}
+	reader := tar.NewReader(limited)
+
+	members := make([]Member, 0)
+	for {
+		header, err := reader.Next()
+		if limited.N <= 0 {
+			return nil, ErrTooLarge
+		}
+		if err == io.EOF {
+			return members, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		member, ok, err := newMember(header.Name, header.Size)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if len(members) >= maxMembers {
+			return nil, ErrTooManyMembers
+		}
+		members = append(members, member)
+	}
+}
+
+// newMember validates the path of a member, rejecting absolute paths and paths
+// escaping the archive (zip-slip), and skips archiver metadata
+func newMember(name string, size int64) (Member, bool, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") ||
+		(len(cleaned) > 1 && cleaned[1] == ':') {
+		return Member{}, false, fmt.Errorf("unsafe member path %q", name)
+	}
+	if strings.HasPrefix(cleaned, "__MACOSX/") || path.Base(cleaned) == ".DS_Store" {
+		return Member{}, false, nil
+	}
+	return Member{Name: cleaned, Size: size}, true, nil
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+
+	"github.com/ijasmoopan/intucloud-task/backend-service/archive"
 )
 
 type Config struct {
@@ -9,25 +11,37 @@ type Config struct {
 	UploadDir         string
 	MaxFileSize       int64
 	AllowedTypes      []string
+	ArchiveLimits     archive.Limits
 	ProcessingService string
 	RedisAddress      string
 	ProcessingChannel string
 	ProgressChannel   string
 	ResultChannel     string
+	FollowChannel     string
+	DetectChannel     string
+	JobChannel        string
 	SupabaseJwtSecret string
 }
 
 func NewConfig() *Config {
 	return &Config{
-		ServerAddress:     getEnvOrDefault("SERVER_ADDRESS", ":8080"),
-		UploadDir:         getEnvOrDefault("UPLOAD_DIR", "../uploads"),
-		MaxFileSize:       500 * 1024 * 1024, // 500MB
-		AllowedTypes:      []string{".log", ".txt", ".csv", ".json"},
+		ServerAddress: getEnvOrDefault("SERVER_ADDRESS", ":8080"),
+		UploadDir:     getEnvOrDefault("UPLOAD_DIR", "../uploads"),
+		MaxFileSize:   500 * 1024 * 1024, // 500MB
+		AllowedTypes:  []string{".log", ".txt", ".csv", ".json", ".gz", ".zst", ".bz2", ".zip", ".tgz"},
+		ArchiveLimits: archive.Limits{
+			MaxMembers:          1000,
+			MaxSize:             4 * 1024 * 1024 * 1024, // 4GB extracted
+			MaxCompressionRatio: 100,
+		},
 		ProcessingService: getEnvOrDefault("PROCESSING_SERVICE_URL", "http://localhost:8081"),
 		RedisAddress:      getEnvOrDefault("REDIS_ADDRESS", "localhost:6379"),
 		ProcessingChannel: getEnvOrDefault("PROCESSING_CHANNEL", "processing_channel"),
 		ProgressChannel:   getEnvOrDefault("PROGRESS_CHANNEL", "progress_channel"),
 		ResultChannel:     getEnvOrDefault("RESULT_CHANNEL", "result_channel"),
+		FollowChannel:     getEnvOrDefault("FOLLOW_CHANNEL", "follow_channel"),
+		DetectChannel:     getEnvOrDefault("DETECT_CHANNEL", "detect_channel"),
+		JobChannel:        getEnvOrDefault("JOB_CHANNEL", "job_channel"),
 		SupabaseJwtSecret: getEnvOrDefault("SUPABASE_JWT_SECRET", ""),
 	}
 }
@@ -58,7 +58,7 @@ func InitDB() (*gorm.DB, error) {
 	}
 
 	// Auto-migrate the models
-	if err := db.AutoMigrate(&models.FileResult{}); err != nil {
+	if err := db.AutoMigrate(&models.FileResult{}, &models.TimelineBucket{}, &models.LogTemplate{}, &models.LogException{}, &models.LogSample{}, &models.LogAnomaly{}, &models.Job{}); err != nil {
 		return nil, fmt.Errorf("failed to perform database migration: %v", err)
 	}
 
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/config"
+	"github.com/ijasmoopan/intucloud-task/backend-service/redis"
+)
+
+// detectTimeout bounds the wait for the log processor to detect a format
+const detectTimeout = 10 * time.Second
+
+// detectMessage asks the log processor for the format of a file, which it
+// answers on the reply channel
+type detectMessage struct {
+	FileName     string `json:"file_name"`
+	ReplyChannel string `json:"reply_channel"`
+}
+
+// detectReply is the answer of the log processor, the detection is passed on
+// as it is
+type detectReply struct {
+	Detection     json.RawMessage `json:"detection,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	ErrorCategory string          `json:"error_category,omitempty"`
+}
+
+// DetectFormat asks the log processor for the format of an uploaded file,
+// detected from a sample of its head, so it can be checked before the file is
+// processed. The response has the format with its confidence and success
+// rate, the rate of every other format and a sample of parsed entries.
+func DetectFormat(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileName := c.Param("name")
+		if !validFileName(fileName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+			return
+		}
+		if _, err := os.Stat(filepath.Join(cfg.UploadDir, fileName)); os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		redisClient, err := redis.NewClient(cfg.RedisAddress)
+		if err != nil {
+			log.Printf("Failed to connect to Redis: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to connect to Redis service",
+			})
+			return
+		}
+		defer redisClient.Close()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), detectTimeout)
+		defer cancel()
+
+		// Subscribe to the reply channel before asking, so the reply is not
+		// published before anyone listens
+		replyChannel := cfg.DetectChannel + ":" + newRequestID()
+		pubsub, err := redisClient.Subscribe(replyChannel)
+		if err == nil {
+			defer pubsub.Close()
+			_, err = pubsub.Receive(ctx)
+		}
+		if err != nil {
+			log.Printf("Failed to subscribe to Redis channel: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to subscribe to detection reply",
+			})
+			return
+		}
+
+		message := detectMessage{FileName: fileName, ReplyChannel: replyChannel}
+		if err := redisClient.Publish(cfg.DetectChannel, message); err != nil {
+			log.Printf("Failed to publish message to Redis: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to publish detect request",
+			})
+			return
+		}
+
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			log.Printf("No detection reply for %s: %v", fileName, err)
+			c.JSON(http.StatusGatewayTimeout, gin.H{
+				"error": "Log processor did not answer in time",
+			})
+			return
+		}
+
+		var reply detectReply
+		if err := json.Unmarshal([]byte(msg.Payload), &reply); err != nil {
+			log.Printf("Invalid detection reply for %s: %v", fileName, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid detection reply"})
+			return
+		}
+		if reply.Error != "" {
+			status := http.StatusUnprocessableEntity
+			if reply.ErrorCategory == "not_found" {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": reply.Error, "error_category": reply.ErrorCategory})
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json; charset=utf-8", reply.Detection)
+	}
+}
+
+// newRequestID creates a random ID that keeps the replies of concurrent
+// requests apart
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/config"
+	"github.com/ijasmoopan/intucloud-task/backend-service/redis"
+)
+
+type FollowRequest struct {
+	FileName string `json:"file_name" binding:"required"`
+	ClientID string `json:"client_id" binding:"required"`
+	FromEnd  bool   `json:"from_end"`
+}
+
+// followMessage starts or stops following a file in the log processor
+type followMessage struct {
+	Action   string `json:"action"`
+	FileName string `json:"file_name"`
+	ClientID string `json:"client_id,omitempty"`
+	FromEnd  bool   `json:"from_end,omitempty"`
+}
+
+// StartFollow asks the log processor to follow an uploaded file that is still
+// being written. Results arrive over the WebSocket as the file grows.
+func StartFollow(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req FollowRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		if !validFileName(req.FileName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+			return
+		}
+		if _, err := os.Stat(filepath.Join(cfg.UploadDir, req.FileName)); os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		message := followMessage{
+			Action:   "start",
+			FileName: req.FileName,
+			ClientID: req.ClientID,
+			FromEnd:  req.FromEnd,
+		}
+		if !publishFollow(c, cfg, message) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":   "Follow request accepted",
+			"file_name": req.FileName,
+			"client_id": req.ClientID,
+		})
+	}
+}
+
+// StopFollow asks the log processor to stop following a file, after which it
+// publishes the final result of the file
+func StopFollow(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileName := c.Param("filename")
+		if !validFileName(fileName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+			return
+		}
+
+		if !publishFollow(c, cfg, followMessage{Action: "stop", FileName: fileName}) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":   "Stop request accepted",
+			"file_name": fileName,
+		})
+	}
+}
+
+// validFileName reports whether a file name names a file of the upload
+// directory
+func validFileName(fileName string) bool {
+	return fileName != "" && !strings.Contains(fileName, "..") && !strings.Contains(fileName, "/")
+}
+
+// publishFollow sends a follow message to the log processor, responding with
+// an error if that fails
+func publishFollow(c *gin.Context, cfg *config.Config, message followMessage) bool {
+	redisClient, err := redis.NewClient(cfg.RedisAddress)
+	if err != nil {
+		log.Printf("Failed to connect to Redis: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to connect to Redis service",
+		})
+		return false
+	}
+	defer redisClient.Close()
+
+	if err := redisClient.Publish(cfg.FollowChannel, message); err != nil {
+		log.Printf("Failed to publish message to Redis: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to publish follow request",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/models"
+	"gorm.io/gorm"
+)
+
+type JobResponse struct {
+	models.Job
+	Results []models.FileResult `json:"Results"`
+
+	// Superseded are the files of the job whose results a later job
+	// replaced by processing them again
+	Superseded []SupersededResult `json:"Superseded,omitempty"`
+}
+
+// SupersededResult is a file of a job whose stored result now belongs to the
+// job that processed it again
+type SupersededResult struct {
+	FileName string `json:"file_name"`
+	JobID    string `json:"job_id"`
+}
+
+// GetJob returns a processing job by its job ID, with the status and counts of
+// its files, its totals, duration and throughput once it ended, and the
+// results stored for its files. Only the latest result of a file is stored, so
+// a file processed again by a later job is listed as superseded instead, its
+// status and counts in this job remaining in the job's files.
+func GetJob(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID := c.Param("id")
+
+		var job models.Job
+		if err := db.Where("job_id = ?", jobID).First(&job).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Job not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch job",
+			})
+			return
+		}
+
+		results := make([]models.FileResult, 0)
+		if err := db.Where("job_id = ?", job.JobID).Order("file_name ASC").Find(&results).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch job results",
+			})
+			return
+		}
+
+		superseded, err := supersededResults(db, &job, results)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch job results",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, JobResponse{Job: job, Results: results, Superseded: superseded})
+	}
+}
+
+// supersededResults returns the files of a job without a result of the job
+// whose result was stored by another job since, leaving out those of earlier
+// jobs that the job has not replaced yet
+func supersededResults(db *gorm.DB, job *models.Job, results []models.FileResult) ([]SupersededResult, error) {
+	stored := make(map[string]bool, len(results))
+	for _, result := range results {
+		stored[result.FileName] = true
+	}
+	var missing []string
+	for _, fileName := range job.FileNames {
+		if !stored[fileName] {
+			missing = append(missing, fileName)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	var moved []models.FileResult
+	if err := db.Where("file_name IN ? AND job_id <> ? AND updated_at > ?", missing, job.JobID, job.CreatedAt).Order("file_name ASC").Find(&moved).Error; err != nil {
+		return nil, err
+	}
+	superseded := make([]SupersededResult, len(moved))
+	for i, result := range moved {
+		superseded[i] = SupersededResult{FileName: result.FileName, JobID: result.JobID}
+	}
+	return superseded, nil
+}
@@ -6,17 +6,57 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ijasmoopan/intucloud-task/backend-service/config"
+	"github.com/ijasmoopan/intucloud-task/backend-service/models"
 	"github.com/ijasmoopan/intucloud-task/backend-service/redis"
+	"gorm.io/gorm"
 )
 
 type ProcessRequest struct {
 	FileNames []string `json:"file_names" binding:"required"`
 	ClientID  string   `json:"client_id" binding:"required"`
+
+	// Format is the format all files are read in, one of logFormats. If
+	// empty it is told by the extension of each file.
+	Format string `json:"format,omitempty"`
+
+	// Columns maps the columns of CSV files, which are otherwise detected
+	// from their header
+	Columns *ColumnMapping `json:"columns,omitempty"`
+
+	// Filters select the entries analysed, the lines of the others are only
+	// counted as excluded
+	Filters *Filters `json:"filters,omitempty"`
+
+	// Index builds the search index of every file that is not an archive
+	Index bool `json:"index,omitempty"`
+}
+
+// logFormats are the formats the log processor reads
+var logFormats = []string{"text", "csv", "json", "access", "syslog", "logfmt"}
+
+// ColumnMapping names the timestamp, level and message columns of CSV files
+type ColumnMapping struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Filters exclude the entries outside a time range, of other levels, whose
+// message does not match a regular expression or whose payload fields do not
+// have the given values
+type Filters struct {
+	From    *time.Time     `json:"from,omitempty"`
+	To      *time.Time     `json:"to,omitempty"`
+	Levels  []string       `json:"levels,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
 }
 
 type ProcessResult struct {
@@ -30,7 +70,9 @@ type ProcessResponse struct {
 	Message string `json:"message"`
 }
 
-func ProcessFiles(cfg *config.Config) gin.HandlerFunc {
+// ProcessFiles asks the log processor to process uploaded files as one job,
+// whose ID is returned to follow it with GET /jobs/:id
+func ProcessFiles(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != http.MethodPost {
 			c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Method not allowed"})
@@ -43,6 +85,21 @@ func ProcessFiles(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if req.Format != "" && !slices.Contains(logFormats, req.Format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown format, expected one of " + strings.Join(logFormats, ", ")})
+			return
+		}
+		if req.Filters != nil {
+			if req.Filters.From != nil && req.Filters.To != nil && !req.Filters.From.Before(*req.Filters.To) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Filter from must be before to"})
+				return
+			}
+			if _, err := regexp.Compile(req.Filters.Message); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message filter: " + err.Error()})
+				return
+			}
+		}
+
 		validFiles := make([]string, 0)
 		for _, fileName := range req.FileNames {
 			if strings.Contains(fileName, "..") || strings.Contains(fileName, "/") {
@@ -71,18 +128,45 @@ func ProcessFiles(cfg *config.Config) gin.HandlerFunc {
 		}
 		defer redisClient.Close()
 
-		// Create a message with the valid file names and client ID
+		// The job is recorded before it is published, so its summary always
+		// finds it
+		job := models.Job{
+			JobID:     newRequestID(),
+			ClientID:  req.ClientID,
+			Status:    "processing",
+			FileNames: validFiles,
+		}
+		if err := db.Create(&job).Error; err != nil {
+			log.Printf("Failed to store job: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to store processing job",
+			})
+			return
+		}
+
+		// Create a message with the job ID, the valid file names and client ID
 		message := struct {
-			Files    []string `json:"file_names"`
-			ClientID string   `json:"client_id"`
+			JobID    string         `json:"job_id"`
+			Files    []string       `json:"file_names"`
+			ClientID string         `json:"client_id"`
+			Format   string         `json:"format,omitempty"`
+			Columns  *ColumnMapping `json:"columns,omitempty"`
+			Filters  *Filters       `json:"filters,omitempty"`
+			Index    bool           `json:"index,omitempty"`
 		}{
+			JobID:    job.JobID,
 			Files:    validFiles,
 			ClientID: req.ClientID,
+			Format:   req.Format,
+			Columns:  req.Columns,
+			Filters:  req.Filters,
+			Index:    req.Index,
 		}
 
 		// Publish the message to Redis
 		if err := redisClient.Publish(cfg.ProcessingChannel, message); err != nil {
 			log.Printf("Failed to publish message to Redis: %v", err)
+			db.Model(&job).Updates(models.Job{Status: "failed", Error: "Failed to publish processing request"})
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to publish processing request",
 			})
@@ -93,6 +177,7 @@ func ProcessFiles(cfg *config.Config) gin.HandlerFunc {
 		c.JSON(http.StatusAccepted, gin.H{
 			"message":   "Processing request accepted",
 			"client_id": req.ClientID,
+			"job_id":    job.JobID,
 		})
 	}
 }
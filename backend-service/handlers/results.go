@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ijasmoopan/intucloud-task/backend-service/models"
@@ -34,8 +35,14 @@ func GetResults(db *gorm.DB) gin.HandlerFunc {
 		var results []models.FileResult
 		var totalItems int64
 
+		// Optionally list only the members of one archive
+		query := db.Model(&models.FileResult{})
+		if archive := c.Query("archive"); archive != "" {
+			query = query.Where("archive = ?", archive)
+		}
+
 		// Get total count
-		if err := db.Model(&models.FileResult{}).Count(&totalItems).Error; err != nil {
+		if err := query.Count(&totalItems).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to count results",
 			})
@@ -46,7 +53,7 @@ func GetResults(db *gorm.DB) gin.HandlerFunc {
 		offset := (params.Page - 1) * params.PageSize
 
 		// Get paginated results
-		if err := db.Offset(offset).Limit(params.PageSize).Order("created_at DESC").Find(&results).Error; err != nil {
+		if err := query.Offset(offset).Limit(params.PageSize).Order("created_at DESC").Find(&results).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to fetch results",
 			})
@@ -68,13 +75,20 @@ func GetResults(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// GetResultByID returns a single file processing result by ID
+// GetResultByID returns a single file processing result by ID, with its
+// templates, exceptions and anomalies
 func GetResultByID(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
 		var result models.FileResult
-		if err := db.First(&result, id).Error; err != nil {
+		if err := db.Preload("Templates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("level ASC, count DESC")
+		}).Preload("Exceptions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("count DESC")
+		}).Preload("Anomalies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_time ASC, level ASC")
+		}).First(&result, id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{
 					"error": "Result not found",
@@ -91,10 +105,11 @@ func GetResultByID(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// GetResultByFilename returns a single file processing result by filename
+// GetResultByFilename returns a single file processing result by filename.
+// Members of archives are named "archive/path/of/member".
 func GetResultByFilename(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		filename := c.Param("filename")
+		filename := strings.TrimPrefix(c.Param("filename"), "/")
 
 		var result models.FileResult
 		if err := db.Where("file_name = ?", filename).Order("updated_at DESC").First(&result).Error; err != nil {
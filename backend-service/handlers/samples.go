@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/config"
+	"github.com/ijasmoopan/intucloud-task/backend-service/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultContextLines and maxContextLines bound the lines returned on
+	// each side of a sample
+	defaultContextLines = 5
+	maxContextLines     = 50
+
+	// contextWindow bounds the bytes read before a sample of a plain file to
+	// find the lines before it
+	contextWindow = 64 * 1024
+	// maxContextLength bounds the text returned of a context line
+	maxContextLength = 4096
+)
+
+// errSampleMoved is returned when the file no longer holds the sample at its
+// offset, as it changed since it was processed
+var errSampleMoved = errors.New("sample not found at its offset")
+
+type Sample struct {
+	ID     uint   `json:"id"`
+	Level  string `json:"level"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+	Random bool   `json:"random,omitempty"`
+}
+
+type SamplesResponse struct {
+	ResultID uint     `json:"result_id"`
+	FileName string   `json:"file_name"`
+	Samples  []Sample `json:"samples"`
+}
+
+type ContextLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Sample bool   `json:"sample,omitempty"`
+}
+
+type SampleContextResponse struct {
+	Sample   Sample        `json:"sample"`
+	FileName string        `json:"file_name"`
+	Lines    []ContextLine `json:"lines"`
+}
+
+// GetResultSamples returns the sample lines kept for a file processing
+// result, the first lines of every level and some picked at random. The
+// optional "level" query parameter keeps the samples of one level.
+func GetResultSamples(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, ok := findResult(c, db)
+		if !ok {
+			return
+		}
+
+		query := db.Where("file_result_id = ?", result.ID)
+		if level := c.Query("level"); level != "" {
+			query = query.Where("level = ?", strings.ToUpper(level))
+		}
+
+		var rows []models.LogSample
+		if err := query.Order("level ASC, line ASC").Find(&rows).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch samples",
+			})
+			return
+		}
+
+		samples := make([]Sample, len(rows))
+		for i, row := range rows {
+			samples[i] = newSample(row)
+		}
+		c.JSON(http.StatusOK, SamplesResponse{
+			ResultID: result.ID,
+			FileName: result.FileName,
+			Samples:  samples,
+		})
+	}
+}
+
+// GetSampleContext returns a sample line with the lines around it, read from
+// the uploaded file. The optional "lines" query parameter sets the number of
+// lines on each side, 5 by default. Compressed files are read from their
+// start, and members of archives have no context.
+func GetSampleContext(cfg *config.Config, db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, ok := findResult(c, db)
+		if !ok {
+			return
+		}
+
+		sampleID, err := strconv.ParseUint(c.Param("sample"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID"})
+			return
+		}
+		var sample models.LogSample
+		if err := db.Where("file_result_id = ?", result.ID).First(&sample, sampleID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Sample not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch sample",
+			})
+			return
+		}
+
+		count := defaultContextLines
+		if lines := c.Query("lines"); lines != "" {
+			n, err := strconv.Atoi(lines)
+			if err != nil || n < 0 || n > maxContextLines {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "lines must be between 0 and " + strconv.Itoa(maxContextLines),
+				})
+				return
+			}
+			count = n
+		}
+
+		if result.Archive != "" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Context is not available for members of archives",
+			})
+			return
+		}
+		if !validFileName(result.FileName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+			return
+		}
+		file, err := os.Open(filepath.Join(cfg.UploadDir, result.FileName))
+		if err != nil {
+			if os.IsNotExist(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+			return
+		}
+		defer file.Close()
+
+		source, start, err := contextSource(file, sample.Offset)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		lines, err := readContext(source, start, sample, count)
+		if errors.Is(err, errSampleMoved) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "The file changed since it was processed",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+
+		c.JSON(http.StatusOK, SampleContextResponse{
+			Sample:   newSample(sample),
+			FileName: result.FileName,
+			Lines:    lines,
+		})
+	}
+}
+
+// findResult loads the result named by the id parameter, answering the
+// request if there is none
+func findResult(c *gin.Context, db *gorm.DB) (models.FileResult, bool) {
+	var result models.FileResult
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+		return result, false
+	}
+	if err := db.First(&result, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Result not found",
+			})
+			return result, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch result",
+		})
+		return result, false
+	}
+	return result, true
+}
+
+func newSample(row models.LogSample) Sample {
+	return Sample{
+		ID:     row.ID,
+		Level:  row.Level,
+		Line:   row.Line,
+		Offset: row.Offset,
+		Text:   row.Text,
+		Random: row.Random,
+	}
+}
+
+// contextSource returns a reader of the file from the offset it starts at.
+// Plain files are read from a window before the sample, compressed ones are
+// decompressed from their start, as offsets count decompressed bytes.
+func contextSource(file *os.File, offset int64) (io.Reader, int64, error) {
+	header := make([]byte, 4)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(file)
+		return reader, 0, err
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return bzip2.NewReader(file), 0, nil
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, 0, errors.New("context is not available for zstd compressed files")
+	}
+
+	start := max(0, offset-contextWindow)
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return file, start, nil
+}
+
+// readContext reads the line holding the offset of a sample, and up to count
+// lines on each side of it, from a reader of the file starting at start. The
+// first line read is skipped unless it starts the file, as it may be cut.
+func readContext(source io.Reader, start int64, sample models.LogSample, count int) ([]ContextLine, error) {
+	reader := bufio.NewReader(source)
+	var before []string
+	position := start
+	for first := true; ; first = false {
+		text, size, err := readContextLine(reader)
+		if size == 0 {
+			if err == nil || err == io.EOF {
+				return nil, errSampleMoved
+			}
+			return nil, err
+		}
+		lineStart, end := position, position+size
+		position = end
+
+		if end <= sample.Offset {
+			if !first || start == 0 {
+				before = append(before, text)
+				if len(before) > count {
+					before = before[1:]
+				}
+			}
+			continue
+		}
+
+		// The line holds the offset, which must still hold the sample
+		want, _, _ := strings.Cut(sample.Text, "\n")
+		want = strings.TrimRight(want, "\r")
+		at := int(sample.Offset - lineStart)
+		switch {
+		case at < len(text):
+			if !strings.HasPrefix(text[at:], want[:min(len(want), len(text)-at)]) {
+				return nil, errSampleMoved
+			}
+		case len(text) < maxContextLength:
+			// Only the text of lines cut to the maximum length is missing
+			return nil, errSampleMoved
+		}
+
+		lines := make([]ContextLine, 0, len(before)+1+count)
+		for i, line := range before {
+			lines = append(lines, ContextLine{Line: sample.Line - len(before) + i, Text: line})
+		}
+		lines = append(lines, ContextLine{Line: sample.Line, Text: text, Sample: true})
+		for i := 1; i <= count; i++ {
+			text, size, err := readContextLine(reader)
+			if size == 0 {
+				if err != nil && err != io.EOF {
+					return nil, err
+				}
+				break
+			}
+			lines = append(lines, ContextLine{Line: sample.Line + i, Text: text})
+		}
+		return lines, nil
+	}
+}
+
+// readContextLine reads a line without its line ending, cut to the maximum
+// context length, and the number of bytes it occupied
+func readContextLine(reader *bufio.Reader) (string, int64, error) {
+	var line []byte
+	var size int64
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += int64(len(chunk))
+		if len(line) < maxContextLength {
+			line = append(line, chunk[:min(len(chunk), maxContextLength-len(line))]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		return string(line), size, err
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/config"
+	"github.com/ijasmoopan/intucloud-task/backend-service/pagination"
+	"github.com/ijasmoopan/intucloud-task/backend-service/search"
+)
+
+type SearchResponse struct {
+	FileName   string       `json:"file_name"`
+	Query      string       `json:"query"`
+	Hits       []search.Hit `json:"hits"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalItems int          `json:"total_items"`
+	TotalPages int          `json:"total_pages"`
+	HasNext    bool         `json:"has_next"`
+	HasPrev    bool         `json:"has_prev"`
+}
+
+// SearchFile searches an uploaded file with the index the log processor built
+// when it processed the file with indexing enabled. The "q" query parameter
+// holds terms and "quoted phrases" combined with AND, OR and NOT and grouped
+// by parentheses, the hits are the matching lines in file order, paginated by
+// "page" and "page_size".
+func SearchFile(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileName := c.Param("name")
+		if !validFileName(fileName) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+			return
+		}
+
+		params, err := pagination.GetPaginationParams(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		queryText := c.Query("q")
+		query, err := search.Parse(queryText)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filePath := filepath.Join(cfg.UploadDir, fileName)
+		info, err := os.Stat(filePath)
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+			return
+		}
+
+		index, err := search.OpenIndex(search.IndexPath(cfg.UploadDir, fileName))
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "File is not indexed, process it with index enabled",
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to open index of %s: %v", fileName, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open index"})
+			return
+		}
+		defer index.Close()
+
+		if index.SourceSize != info.Size() {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "The file changed since it was indexed, process it again",
+			})
+			return
+		}
+
+		searcher := search.NewSearcher(index, filePath)
+		units, err := searcher.Search(query)
+		var hits []search.Hit
+		var paginationInfo *pagination.PaginationInfo
+		if err == nil {
+			paginationInfo = pagination.CalculatePagination(len(units), params)
+			startIndex, endIndex := pagination.GetPageIndices(paginationInfo)
+			hits, err = searcher.Hits(units[startIndex:endIndex])
+		}
+		switch {
+		case errors.Is(err, search.ErrQueryTooBroad):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, search.ErrUnsupportedCompression):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			log.Printf("Failed to search %s: %v", fileName, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search file"})
+			return
+		}
+
+		c.JSON(http.StatusOK, SearchResponse{
+			FileName:   fileName,
+			Query:      queryText,
+			Hits:       hits,
+			Page:       paginationInfo.CurrentPage,
+			PageSize:   paginationInfo.PageSize,
+			TotalItems: paginationInfo.TotalItems,
+			TotalPages: paginationInfo.TotalPages,
+			HasNext:    paginationInfo.HasNext,
+			HasPrev:    paginationInfo.HasPrev,
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/models"
+	"gorm.io/gorm"
+)
+
+type TimelinePoint struct {
+	Start  time.Time      `json:"start"`
+	Counts map[string]int `json:"counts"`
+}
+
+type TimelineResponse struct {
+	ResultID   uint            `json:"result_id"`
+	FileName   string          `json:"file_name"`
+	BucketSize string          `json:"bucket_size"`
+	Buckets    []TimelinePoint `json:"buckets"`
+}
+
+// GetResultTimeline returns the per level line counts over time for a file processing result.
+// The optional "bucket" query parameter re-buckets the series into a coarser duration that
+// must be a multiple of the stored one, "from" and "to" (RFC3339) limit the time range to the
+// stored buckets overlapping it.
+func GetResultTimeline(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var result models.FileResult
+		if err := db.First(&result, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Result not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch result",
+			})
+			return
+		}
+
+		query := db.Where("file_result_id = ?", result.ID)
+		if from := c.Query("from"); from != "" {
+			fromTime, err := time.Parse(time.RFC3339, from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid from parameter, expected RFC3339",
+				})
+				return
+			}
+			// A bucket starting before from still overlaps the range until it ends
+			query = query.Where("bucket_start + bucket_seconds * interval '1 second' > ?", fromTime)
+		}
+		if to := c.Query("to"); to != "" {
+			toTime, err := time.Parse(time.RFC3339, to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid to parameter, expected RFC3339",
+				})
+				return
+			}
+			query = query.Where("bucket_start < ?", toTime)
+		}
+
+		var buckets []models.TimelineBucket
+		if err := query.Order("bucket_start ASC").Find(&buckets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch timeline",
+			})
+			return
+		}
+
+		var storedSize time.Duration
+		if len(buckets) > 0 {
+			storedSize = time.Duration(buckets[0].BucketSeconds) * time.Second
+		}
+
+		bucketSize := storedSize
+		if bucket := c.Query("bucket"); bucket != "" {
+			size, err := time.ParseDuration(bucket)
+			if err != nil || size <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid bucket parameter",
+				})
+				return
+			}
+			if storedSize > 0 && size%storedSize != 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "bucket must be a multiple of " + storedSize.String(),
+				})
+				return
+			}
+			bucketSize = size
+		}
+
+		points := make([]TimelinePoint, 0)
+		for _, bucket := range buckets {
+			start := bucket.BucketStart.UTC().Truncate(bucketSize)
+			if len(points) == 0 || !points[len(points)-1].Start.Equal(start) {
+				points = append(points, TimelinePoint{Start: start, Counts: make(map[string]int)})
+			}
+			points[len(points)-1].Counts[bucket.Level] += bucket.Count
+		}
+
+		response := TimelineResponse{
+			ResultID: result.ID,
+			FileName: result.FileName,
+			Buckets:  points,
+		}
+		if bucketSize > 0 {
+			response.BucketSize = bucketSize.String()
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
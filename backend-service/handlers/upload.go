@@ -4,18 +4,21 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ijasmoopan/intucloud-task/backend-service/archive"
 	"github.com/ijasmoopan/intucloud-task/backend-service/config"
 )
 
 type UploadFileInfo struct {
-	Filename string `json:"filename"`
-	Size     int64  `json:"size"`
-	Path     string `json:"path"`
+	Filename string           `json:"filename"`
+	Size     int64            `json:"size"`
+	Path     string           `json:"path"`
+	Members  []archive.Member `json:"members,omitempty"`
 }
 
 func UploadFile(cfg *config.Config) gin.HandlerFunc {
@@ -32,16 +35,29 @@ func UploadFile(cfg *config.Config) gin.HandlerFunc {
 		uploadedFiles := files.([]*multipart.FileHeader)
 		uploadedFileInfos := make([]UploadFileInfo, 0)
 
+		// A rejected upload removes the files it already saved, so it either
+		// saves all of its files or none
+		removeSaved := func() {
+			for _, info := range uploadedFileInfos {
+				os.Remove(info.Path)
+			}
+		}
+
 		// Process each file
 		for _, file := range uploadedFiles {
-			// Generate unique filename
-			ext := filepath.Ext(file.Filename)
+			// Generate unique filename, keeping the double extension of tarballs
+			ext := archive.Extension(file.Filename)
+			if ext == "" {
+				ext = filepath.Ext(file.Filename)
+			}
 			nameWithoutExt := strings.TrimSuffix(file.Filename, ext)
 			filename := fmt.Sprintf("%s_%s%s", nameWithoutExt, time.Now().Format("20060102150405"), ext)
 			dst := filepath.Join(cfg.UploadDir, filename)
 
 			// Save the file
 			if err := c.SaveUploadedFile(file, dst); err != nil {
+				os.Remove(dst)
+				removeSaved()
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error": "Failed to save file",
 					"file":  file.Filename,
@@ -49,10 +65,27 @@ func UploadFile(cfg *config.Config) gin.HandlerFunc {
 				return
 			}
 
+			// Register the members of archives, rejecting unsafe ones
+			var members []archive.Member
+			if archive.Extension(filename) != "" {
+				var err error
+				members, err = archive.Inspect(dst, cfg.ArchiveLimits)
+				if err != nil {
+					os.Remove(dst)
+					removeSaved()
+					c.JSON(http.StatusBadRequest, gin.H{
+						"error": fmt.Sprintf("Invalid archive: %v", err),
+						"file":  file.Filename,
+					})
+					return
+				}
+			}
+
 			uploadedFileInfos = append(uploadedFileInfos, UploadFileInfo{
 				Filename: filename,
 				Size:     file.Size,
 				Path:     dst,
+				Members:  members,
 			})
 		}
 
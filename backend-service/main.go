@@ -75,11 +75,19 @@ func main() {
 	{
 		api.POST("/upload", middleware.AuthMiddleware(), middleware.ValidateFiles(), handlers.UploadFile(cfg))
 		api.GET("/files", middleware.AuthMiddleware(), handlers.ListFiles(cfg))
-		api.POST("/process", middleware.AuthMiddleware(), handlers.ProcessFiles(cfg))
+		api.GET("/files/:name/detect", middleware.AuthMiddleware(), handlers.DetectFormat(cfg))
+		api.GET("/files/:name/search", middleware.AuthMiddleware(), handlers.SearchFile(cfg))
+		api.POST("/process", middleware.AuthMiddleware(), handlers.ProcessFiles(cfg, db))
+		api.GET("/jobs/:id", middleware.AuthMiddleware(), handlers.GetJob(db))
+		api.POST("/follow", middleware.AuthMiddleware(), handlers.StartFollow(cfg))
+		api.DELETE("/follow/:filename", middleware.AuthMiddleware(), handlers.StopFollow(cfg))
 		api.GET("/ws", wsManager.HandleWebSocket)
 		api.GET("/results", middleware.AuthMiddleware(), handlers.GetResults(db))
 		api.GET("/results/:id", middleware.AuthMiddleware(), handlers.GetResultByID(db))
-		api.GET("/results/filename/:filename", middleware.AuthMiddleware(), handlers.GetResultByFilename(db))
+		api.GET("/results/:id/timeline", middleware.AuthMiddleware(), handlers.GetResultTimeline(db))
+		api.GET("/results/:id/samples", middleware.AuthMiddleware(), handlers.GetResultSamples(db))
+		api.GET("/results/:id/samples/:sample/context", middleware.AuthMiddleware(), handlers.GetSampleContext(cfg, db))
+		api.GET("/results/filename/*filename", middleware.AuthMiddleware(), handlers.GetResultByFilename(db))
 	}
 
 	// Start server
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -12,6 +14,30 @@ import (
 	"github.com/ijasmoopan/intucloud-task/backend-service/config"
 )
 
+// compressionMagic holds the leading bytes every file of a compressed or archive type must start with
+var compressionMagic = map[string][]byte{
+	".gz":  {0x1f, 0x8b},
+	".zst": {0x28, 0xb5, 0x2f, 0xfd},
+	".bz2": []byte("BZh"),
+	".tgz": {0x1f, 0x8b},
+	".zip": []byte("PK\x03\x04"),
+}
+
+// hasMagic reports whether an uploaded file starts with the given bytes
+func hasMagic(file *multipart.FileHeader, magic []byte) bool {
+	f, err := file.Open()
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, magic)
+}
+
 func ValidateFiles() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		cfg := config.NewConfig()
@@ -61,6 +87,16 @@ func ValidateFiles() gin.HandlerFunc {
 				return
 			}
 
+			// Compressed files must really be in the format their extension claims
+			if magic, ok := compressionMagic[ext]; ok && !hasMagic(file, magic) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "File content does not match its compression type",
+					"file":  file.Filename,
+				})
+				c.Abort()
+				return
+			}
+
 			validFiles = append(validFiles, file)
 		}
 
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LogAnomaly is a period of a processed file whose error or warn rate rose
+// sharply above the rolling baseline of the time before it
+type LogAnomaly struct {
+	ID           uint      `gorm:"primarykey"`
+	FileResultID uint      `gorm:"not null;index"`
+	Level        string    `gorm:"not null"`
+	StartTime    time.Time `gorm:"not null"`
+	EndTime      time.Time `gorm:"not null"`
+	Lines        int       `gorm:"not null"`
+	Count        int       `gorm:"not null"`
+	Observed     float64   `gorm:"not null"`
+	Expected     float64   `gorm:"not null"`
+	Score        float64   `gorm:"not null"`
+	Severity     string    `gorm:"not null;index"`
+}
+
+func (LogAnomaly) TableName() string {
+	return "log_anomalies"
+}
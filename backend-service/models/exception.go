@@ -0,0 +1,18 @@
+package models
+
+// LogException is the stack traces of one exception type and top frame found
+// in a processed file
+type LogException struct {
+	ID           uint           `gorm:"primarykey"`
+	FileResultID uint           `gorm:"not null;index"`
+	Type         string         `gorm:"not null"`
+	TopFrame     string         `gorm:"type:text"`
+	Count        int            `gorm:"not null"`
+	Levels       map[string]int `gorm:"type:jsonb;serializer:json"`
+	Message      string         `gorm:"type:text"`
+	Frames       []string       `gorm:"type:jsonb;serializer:json"`
+}
+
+func (LogException) TableName() string {
+	return "log_exceptions"
+}
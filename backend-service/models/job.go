@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job is a processing request for one or more files, created when it is
+// accepted with status processing and completed by the summary the log
+// processor publishes once it ends. The results of its files carry its job ID,
+// which is not a foreign key as followed files and jobs the log processor
+// assigned an ID to have no record.
+type Job struct {
+	gorm.Model
+	JobID     string   `gorm:"not null;uniqueIndex"`
+	ClientID  string   `gorm:"not null"`
+	Status    string   `gorm:"not null"`
+	Error     string   `gorm:"type:text"`
+	FileNames []string `gorm:"type:jsonb;serializer:json"`
+
+	// Files are the statuses of the files with their main counts, archives
+	// count as one file
+	Files []JobFile `gorm:"type:jsonb;serializer:json"`
+
+	TotalLines     *int           `gorm:"default:null"`
+	TotalBytes     *int64         `gorm:"default:null"`
+	ErrorCount     *int           `gorm:"default:null"`
+	WarnCount      *int           `gorm:"default:null"`
+	Levels         map[string]int `gorm:"type:jsonb;serializer:json"`
+	Entries        *int           `gorm:"default:null"`
+	MalformedLines *int           `gorm:"default:null"`
+
+	// Resumed jobs were interrupted by a restart of the log processor, their
+	// duration and throughput cover the run after it
+	Resumed        bool       `gorm:"not null;default:false"`
+	StartedAt      *time.Time `gorm:"default:null"`
+	FinishedAt     *time.Time `gorm:"default:null"`
+	DurationMs     *int64     `gorm:"default:null"`
+	LinesPerSecond *float64   `gorm:"default:null"`
+	BytesPerSecond *float64   `gorm:"default:null"`
+}
+
+// JobFile is the status of a file of a job
+type JobFile struct {
+	FileName      string `json:"file_name"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	ErrorCategory string `json:"error_category,omitempty"`
+	TotalLines    int    `json:"total_lines"`
+	TotalBytes    int64  `json:"total_bytes"`
+	ErrorCount    int    `json:"error_count"`
+	WarnCount     int    `json:"warn_count"`
+	Members       int    `json:"members,omitempty"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -8,11 +10,47 @@ import (
 type FileResult struct {
 	gorm.Model
 	FileName   string `gorm:"not null;uniqueIndex"`
+	Archive    string `gorm:"index"`
 	ClientID   string `gorm:"not null"`
+	JobID      string `gorm:"index"`
 	Status     string `gorm:"not null"`
 	WarnCount  *int   `gorm:"default:null"`
 	ErrorCount *int   `gorm:"default:null"`
 	Error      string `gorm:"type:text"`
+
+	// ErrorCategory tells why a failed file could not be processed, e.g.
+	// not_found, permission, decode or panic
+	ErrorCategory string `gorm:"default:null"`
+
+	// Format is the format the file was read in, such as text, csv or
+	// access. Columns maps the timestamp, level and message of CSV files to
+	// the columns they were read from.
+	Format  string            `gorm:"default:null"`
+	Columns map[string]string `gorm:"type:jsonb;serializer:json"`
+
+	// Excluded counts the lines excluded by the filters of the request, by
+	// filter: time, level, message or fields
+	Excluded map[string]int `gorm:"type:jsonb;serializer:json"`
+
+	// Indexed is set once the file was indexed for search, IndexError tells
+	// why the index requested for it is missing
+	Indexed    bool
+	IndexError string `gorm:"type:text"`
+
+	Levels            map[string]int `gorm:"type:jsonb;serializer:json"`
+	TotalLines        *int           `gorm:"default:null"`
+	TotalBytes        *int64         `gorm:"default:null"`
+	MalformedLines    *int           `gorm:"default:null"`
+	OversizedLines    *int           `gorm:"default:null"`
+	Entries           *int           `gorm:"default:null"`
+	ContinuationLines *int           `gorm:"default:null"`
+	LongestLine       *int           `gorm:"default:null"`
+	FirstTimestamp    *time.Time     `gorm:"default:null"`
+	LastTimestamp     *time.Time     `gorm:"default:null"`
+
+	Templates  []LogTemplate  `gorm:"foreignKey:FileResultID" json:"Templates,omitempty"`
+	Exceptions []LogException `gorm:"foreignKey:FileResultID" json:"Exceptions,omitempty"`
+	Anomalies  []LogAnomaly   `gorm:"foreignKey:FileResultID" json:"Anomalies,omitempty"`
 }
 
 func (FileResult) TableName() string {
@@ -0,0 +1,17 @@
+package models
+
+// LogSample is a line kept as an example of its level in a processed file,
+// located by its line number and the byte offset of its start
+type LogSample struct {
+	ID           uint   `gorm:"primarykey"`
+	FileResultID uint   `gorm:"not null;index"`
+	Level        string `gorm:"not null;index"`
+	Line         int    `gorm:"not null"`
+	Offset       int64  `gorm:"not null"`
+	Text         string `gorm:"type:text"`
+	Random       bool   `gorm:"not null;default:false"`
+}
+
+func (LogSample) TableName() string {
+	return "log_samples"
+}
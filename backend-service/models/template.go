@@ -0,0 +1,15 @@
+package models
+
+// LogTemplate is a recurring message pattern found in a processed file
+type LogTemplate struct {
+	ID           uint   `gorm:"primarykey"`
+	FileResultID uint   `gorm:"not null;index"`
+	Level        string `gorm:"not null"`
+	Template     string `gorm:"type:text;not null"`
+	Count        int    `gorm:"not null"`
+	Example      string `gorm:"type:text"`
+}
+
+func (LogTemplate) TableName() string {
+	return "log_templates"
+}
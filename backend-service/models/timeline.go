@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TimelineBucket is the number of lines of one level within a time bucket of a processed file
+type TimelineBucket struct {
+	ID            uint      `gorm:"primarykey"`
+	FileResultID  uint      `gorm:"not null;index"`
+	BucketStart   time.Time `gorm:"not null;index"`
+	BucketSeconds int64     `gorm:"not null"`
+	Level         string    `gorm:"not null"`
+	Count         int       `gorm:"not null"`
+}
+
+func (TimelineBucket) TableName() string {
+	return "log_timelines"
+}
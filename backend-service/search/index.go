@@ -0,0 +1,254 @@
+package search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// indexDir is the directory of the upload directory the log processor
+	// saves the indexes of uploaded files in, named after the file with
+	// indexExtension
+	indexDir       = ".index"
+	indexExtension = ".idx"
+
+	// minTermLength and maxTermLength bound the terms the log processor
+	// indexes
+	minTermLength = 2
+	maxTermLength = 64
+
+	unitSize    = 12
+	trailerSize = 48
+)
+
+// indexMagic starts and ends index files, its last byte is the version
+var indexMagic = []byte("LPIDX\x00\n\x01")
+
+// ErrCorruptIndex is returned for index files that cannot be read
+var ErrCorruptIndex = errors.New("corrupt index")
+
+// IndexPath returns the path of the index of an uploaded file
+func IndexPath(uploadDir, fileName string) string {
+	return filepath.Join(uploadDir, indexDir, fileName+indexExtension)
+}
+
+// Index is the inverted index of an uploaded file written by the log
+// processor, which maps the lower-cased terms of the lines of the file to
+// the lines they appear in. Lines are numbered in the order of the file and
+// are CSV records and JSON values for those formats.
+type Index struct {
+	file *os.File
+
+	// SourceSize is the size of the file when it was indexed, Units the
+	// number of lines indexed
+	SourceSize int64
+	Units      int
+
+	postingsStart int64
+	dictStart     int64
+	blocksStart   int64
+	blocksEnd     int64
+	blocks        []indexBlock
+}
+
+// indexBlock is a block of the dictionary, located by its first term
+type indexBlock struct {
+	first  string
+	offset int64
+}
+
+// OpenIndex opens an index file, reading its trailer and block table
+func OpenIndex(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{file: file}
+	if err := index.readTables(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return index, nil
+}
+
+func (ix *Index) readTables() error {
+	info, err := ix.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size < int64(2*len(indexMagic)+trailerSize) {
+		return ErrCorruptIndex
+	}
+
+	head := make([]byte, len(indexMagic))
+	if _, err := ix.file.ReadAt(head, 0); err != nil {
+		return err
+	}
+	tail := make([]byte, trailerSize+len(indexMagic))
+	if _, err := ix.file.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return err
+	}
+	if !bytes.Equal(head, indexMagic) || !bytes.Equal(tail[trailerSize:], indexMagic) {
+		return fmt.Errorf("%w: unknown format or version", ErrCorruptIndex)
+	}
+	values := make([]int64, 6)
+	for i := range values {
+		values[i] = int64(binary.LittleEndian.Uint64(tail[i*8:]))
+	}
+	ix.SourceSize, ix.Units = values[0], int(values[1])
+	ix.postingsStart, ix.dictStart, ix.blocksStart = values[2], values[3], values[4]
+	ix.blocksEnd = size - int64(len(tail))
+	if ix.postingsStart != int64(len(indexMagic))+int64(ix.Units)*unitSize ||
+		ix.postingsStart > ix.dictStart || ix.dictStart > ix.blocksStart || ix.blocksStart > ix.blocksEnd {
+		return ErrCorruptIndex
+	}
+
+	table := make([]byte, ix.blocksEnd-ix.blocksStart)
+	if _, err := ix.file.ReadAt(table, ix.blocksStart); err != nil {
+		return err
+	}
+	ix.blocks = make([]indexBlock, 0, values[5])
+	for len(table) > 0 {
+		var block indexBlock
+		var ok bool
+		if block.first, table, ok = readString(table); !ok {
+			return ErrCorruptIndex
+		}
+		offset, n := binary.Uvarint(table)
+		if n <= 0 {
+			return ErrCorruptIndex
+		}
+		block.offset, table = int64(offset), table[n:]
+		ix.blocks = append(ix.blocks, block)
+	}
+	return nil
+}
+
+// Close closes the index file
+func (ix *Index) Close() error {
+	return ix.file.Close()
+}
+
+// Postings returns the lines holding a term in increasing order, none if the
+// term is not indexed
+func (ix *Index) Postings(term string) ([]uint32, error) {
+	i := sort.Search(len(ix.blocks), func(i int) bool { return ix.blocks[i].first > term }) - 1
+	if i < 0 {
+		return nil, nil
+	}
+	end := ix.blocksStart - ix.dictStart
+	if i+1 < len(ix.blocks) {
+		end = ix.blocks[i+1].offset
+	}
+	block := make([]byte, end-ix.blocks[i].offset)
+	if _, err := ix.file.ReadAt(block, ix.dictStart+ix.blocks[i].offset); err != nil {
+		return nil, err
+	}
+
+	for len(block) > 0 {
+		var entry string
+		var ok bool
+		if entry, block, ok = readString(block); !ok {
+			return nil, ErrCorruptIndex
+		}
+		var values [3]uint64
+		for j := range values {
+			value, n := binary.Uvarint(block)
+			if n <= 0 {
+				return nil, ErrCorruptIndex
+			}
+			values[j], block = value, block[n:]
+		}
+		if entry != term {
+			continue
+		}
+		return ix.readPostings(int64(values[0]), int64(values[1]), int(values[2]))
+	}
+	return nil, nil
+}
+
+func (ix *Index) readPostings(offset, length int64, count int) ([]uint32, error) {
+	data := make([]byte, length)
+	if _, err := ix.file.ReadAt(data, ix.postingsStart+offset); err != nil {
+		return nil, err
+	}
+	postings := make([]uint32, 0, count)
+	var unit uint64
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, ErrCorruptIndex
+		}
+		unit += delta
+		postings = append(postings, uint32(unit))
+		data = data[n:]
+	}
+	return postings, nil
+}
+
+// Unit returns the byte offset of a line of the file and its line number,
+// which differ from its position in the index for CSV records and JSON values
+// spanning lines
+func (ix *Index) Unit(unit int) (int64, int, error) {
+	if unit < 0 || unit >= ix.Units {
+		return 0, 0, fmt.Errorf("%w: line %d out of range", ErrCorruptIndex, unit)
+	}
+	var b [unitSize]byte
+	if _, err := ix.file.ReadAt(b[:], int64(len(indexMagic))+int64(unit)*unitSize); err != nil {
+		return 0, 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b[:8])), int(binary.LittleEndian.Uint32(b[8:])), nil
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	length, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < length {
+		return "", nil, false
+	}
+	return string(b[n : n+int(length)]), b[n+int(length):], true
+}
+
+// appendLower appends text with ASCII letters lower-cased, as the log
+// processor indexes it
+func appendLower(b, text []byte) []byte {
+	for _, c := range text {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// terms returns the terms the log processor indexes of lower-cased text: runs
+// of letters, digits and non-ASCII bytes between minTermLength and
+// maxTermLength long
+func terms(text []byte) []string {
+	var result []string
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && termByte(text[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if n := i - start; n >= minTermLength && n <= maxTermLength {
+				result = append(result, string(text[start:i]))
+			}
+			start = -1
+		}
+	}
+	return result
+}
+
+func termByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c >= 0x80
+}
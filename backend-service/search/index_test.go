@@ -0,0 +1,178 @@
+package search
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The index of testdata/app.log is written by the tests of the log processor,
+// from two chunks of the file merged together
+const (
+	fixtureLog   = "testdata/app.log"
+	fixtureIndex = fixtureLog + indexExtension
+)
+
+func openFixture(t *testing.T) (*Index, []string) {
+	t.Helper()
+	data, err := os.ReadFile(fixtureLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenIndex(fixtureIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	if index.SourceSize != int64(len(data)) {
+		t.Fatalf("index is of %d bytes, the log has %d", index.SourceSize, len(data))
+	}
+	return index, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestIndexUnits(t *testing.T) {
+	index, lines := openFixture(t)
+	if index.Units != len(lines) {
+		t.Fatalf("got %d units, want %d", index.Units, len(lines))
+	}
+	var offset int64
+	for i, line := range lines {
+		gotOffset, gotLine, err := index.Unit(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotOffset != offset || gotLine != i+1 {
+			t.Errorf("unit %d at offset %d, line %d, want %d, %d", i, gotOffset, gotLine, offset, i+1)
+		}
+		offset += int64(len(line)) + 1
+	}
+	if _, _, err := index.Unit(len(lines)); err == nil {
+		t.Error("got a unit past the last line")
+	}
+}
+
+func TestIndexPostings(t *testing.T) {
+	index, lines := openFixture(t)
+	for _, term := range []string{"2025", "error", "payment", "user07", "user99", "db", "10", "zz", "missing", "exhausted"} {
+		var want []uint32
+		for i, line := range lines {
+			if containsTerm(line, term) {
+				want = append(want, uint32(i))
+			}
+		}
+		got, err := index.Postings(term)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("postings of %q are %v, want %v", term, got, want)
+		}
+	}
+}
+
+// containsTerm reports whether the terms of a line include term
+func containsTerm(line, term string) bool {
+	for _, t := range terms(appendLower(nil, []byte(line))) {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSearch(t *testing.T) {
+	index, lines := openFixture(t)
+	has := func(s string) func(string) bool {
+		return func(line string) bool { return strings.Contains(strings.ToLower(line), s) }
+	}
+	tests := []struct {
+		query string
+		match func(line string) bool
+	}{
+		{"payment", has("payment")},
+		{"payment AND NOT declined", func(line string) bool { return has("payment")(line) && !has("declined")(line) }},
+		{"NOT error", func(line string) bool { return !has("error")(line) }},
+		{`"connection timeout"`, has("connection timeout")},
+		{"connection timeout", func(line string) bool { return has("connection")(line) && has("timeout")(line) }},
+		{"user07 OR user08 OR warn", func(line string) bool {
+			return has("user07")(line) || has("user08")(line) || has(" warn ")(line)
+		}},
+		{"(user07 OR user08) AND NOT user08", has("user07")},
+		{"NOT error AND NOT info", has(" warn ")},
+		{"NOT (error OR info) OR user01", func(line string) bool { return has(" warn ")(line) || has("user01")(line) }},
+		{"db-primary", has("db-primary")},
+	}
+	for _, tt := range tests {
+		query, err := Parse(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		searcher := NewSearcher(index, fixtureLog)
+		units, err := searcher.Search(query)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		var want []uint32
+		for i, line := range lines {
+			if tt.match(line) {
+				want = append(want, uint32(i))
+			}
+		}
+		if len(want) == 0 {
+			t.Fatalf("no line of the fixture matches %q", tt.query)
+		}
+		if !reflect.DeepEqual(units, want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, units, want)
+		}
+	}
+}
+
+func TestHits(t *testing.T) {
+	index, lines := openFixture(t)
+	query, err := Parse(`"card declined"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searcher := NewSearcher(index, fixtureLog)
+	units, err := searcher.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last line has no line after it to end it
+	units = append(units, uint32(len(lines)-1))
+	hits, err := searcher.Hits(units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hit := range hits {
+		unit := int(units[i])
+		if hit.Line != unit+1 || hit.Text != lines[unit] {
+			t.Errorf("hit %d is line %d %q, want %d %q", i, hit.Line, hit.Text, unit+1, lines[unit])
+		}
+	}
+}
+
+func TestOpenIndexRejectsCorruptFiles(t *testing.T) {
+	data, err := os.ReadFile(fixtureIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, corrupt := range map[string][]byte{
+		"truncated":   data[:len(data)-1],
+		"bad magic":   append([]byte("XXXXXXXX"), data[len(indexMagic):]...),
+		"bad trailer": append(bytes.Clone(data[:len(data)-len(indexMagic)]), "XXXXXXXX"...),
+		"empty":       nil,
+	} {
+		path := dir + "/" + strings.ReplaceAll(name, " ", "-") + indexExtension
+		if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if index, err := OpenIndex(path); err == nil {
+			index.Close()
+			t.Errorf("opened the %s index", name)
+		}
+	}
+}
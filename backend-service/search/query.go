@@ -0,0 +1,198 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidQuery is returned for queries that cannot be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query is a parsed search query
+type Query struct {
+	root node
+}
+
+type node interface{}
+
+// matchNode matches the lines holding a term or phrase. Those that are not a
+// single indexed term are matched by the lines holding all of their terms
+// whose text holds them.
+type matchNode struct {
+	text   string
+	terms  []string
+	verify bool
+}
+
+type andNode struct{ left, right node }
+
+type orNode struct{ left, right node }
+
+type notNode struct{ node node }
+
+// Parse parses a query of terms and "quoted phrases" combined with AND, OR
+// and NOT, in that order of precedence, and grouped by parentheses. Terms
+// without an operator between them must all match. Matching ignores the case
+// of ASCII letters.
+func Parse(query string) (*Query, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, p.tokens[p.pos].text)
+	}
+	return &Query{root: root}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: query[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexAny(query[i:], " \t\n\r()\"")
+			if end < 0 {
+				end = len(query) - i
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: query[i : i+end]})
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// operator reports whether the next token is the given operator
+func (p *parser) operator(name string) bool {
+	t, ok := p.peek()
+	return ok && t.kind == tokenWord && t.text == name
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.operator("OR") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.operator("AND") {
+			p.pos++
+		} else if t, ok := p.peek(); !ok || t.kind == tokenClose || p.operator("OR") {
+			return left, nil
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+func (p *parser) not() (node, error) {
+	if p.operator("NOT") {
+		p.pos++
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end", ErrInvalidQuery)
+	}
+	p.pos++
+	switch t.kind {
+	case tokenOpen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing )", ErrInvalidQuery)
+		}
+		p.pos++
+		return inner, nil
+	case tokenClose:
+		return nil, fmt.Errorf("%w: unexpected )", ErrInvalidQuery)
+	}
+	if t.kind == tokenWord && (t.text == "AND" || t.text == "OR") {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrInvalidQuery, t.text)
+	}
+
+	text := string(appendLower(nil, []byte(t.text)))
+	match := matchNode{text: text, terms: terms([]byte(text))}
+	if len(match.terms) == 0 {
+		return nil, fmt.Errorf("%w: %q has no term of at least %d letters or digits", ErrInvalidQuery, t.text, minTermLength)
+	}
+	match.verify = len(match.terms) > 1 || match.terms[0] != text
+	return match, nil
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe writes a parsed query with its grouping made explicit
+func describe(n node) string {
+	switch n := n.(type) {
+	case matchNode:
+		if n.verify {
+			return fmt.Sprintf("%q", n.text)
+		}
+		return n.text
+	case notNode:
+		return "NOT " + describe(n.node)
+	case andNode:
+		return "(" + describe(n.left) + " AND " + describe(n.right) + ")"
+	case orNode:
+		return "(" + describe(n.left) + " OR " + describe(n.right) + ")"
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"error", "error"},
+		{"Error TIMEOUT", "(error AND timeout)"},
+		{"a1 OR b1 c1", "(a1 OR (b1 AND c1))"},
+		{"a1 b1 OR c1", "((a1 AND b1) OR c1)"},
+		{"a1 AND b1 OR c1 AND d1", "((a1 AND b1) OR (c1 AND d1))"},
+		{"NOT a1 b1", "(NOT a1 AND b1)"},
+		{"NOT a1 OR b1", "(NOT a1 OR b1)"},
+		{"NOT NOT a1", "NOT NOT a1"},
+		{"a1 AND NOT (b1 OR c1)", "(a1 AND NOT (b1 OR c1))"},
+		{"(a1 OR b1) c1", "((a1 OR b1) AND c1)"},
+		{"a1 OR b1 OR c1", "((a1 OR b1) OR c1)"},
+		{`"Connection Timeout" OR db-primary`, `("connection timeout" OR "db-primary")`},
+		{`"timeout"`, "timeout"},
+		{"user07", "user07"},
+		{"or and", "(or AND and)"},
+	}
+	for _, tt := range tests {
+		query, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if got := describe(query.root); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query"},
+		{"a1 AND", "unexpected end"},
+		{"OR a1", "unexpected OR"},
+		{"a1 OR AND b1", "unexpected AND"},
+		{"NOT", "unexpected end"},
+		{"(a1 OR b1", "missing )"},
+		{"a1)", `unexpected ")"`},
+		{"()", "unexpected )"},
+		{`"unterminated`, "unterminated phrase"},
+		{"a", "no term"},
+		{`"- -"`, "no term"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		if !errors.Is(err, ErrInvalidQuery) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want an invalid query error with %q", tt.query, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxLineLength bounds the text of a line read to match a phrase
+	maxLineLength = 64 * 1024
+	// maxHitLength bounds the text of a line returned with a hit
+	maxHitLength = 4096
+	// maxVerifiedLines bounds the lines read to match the phrases of a
+	// query
+	maxVerifiedLines = 1000000
+)
+
+var (
+	// ErrUnsupportedCompression is returned for files whose lines cannot be
+	// read back
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+	// ErrQueryTooBroad is returned for queries whose phrases would need
+	// too many lines to be read
+	ErrQueryTooBroad = errors.New("query too broad")
+)
+
+// Hit is a line matching a query
+type Hit struct {
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// Searcher evaluates queries against the index of a file, reading the lines
+// of the file to match phrases and to return hits
+type Searcher struct {
+	index    *Index
+	path     string
+	verified int
+}
+
+// NewSearcher returns a searcher of the file at path with its index
+func NewSearcher(index *Index, path string) *Searcher {
+	return &Searcher{index: index, path: path}
+}
+
+// postingSet is a set of lines, or of the lines missing from it if negated,
+// so NOT is evaluated without listing every line of the file
+type postingSet struct {
+	units   []uint32
+	negated bool
+}
+
+// Search returns the lines matching a query in increasing order
+func (s *Searcher) Search(query *Query) ([]uint32, error) {
+	set, err := s.eval(query.root)
+	if err != nil {
+		return nil, err
+	}
+	if !set.negated {
+		return set.units, nil
+	}
+	all := make([]uint32, s.index.Units)
+	for i := range all {
+		all[i] = uint32(i)
+	}
+	return difference(all, set.units), nil
+}
+
+func (s *Searcher) eval(n node) (postingSet, error) {
+	switch n := n.(type) {
+	case matchNode:
+		units, err := s.match(n)
+		return postingSet{units: units}, err
+	case notNode:
+		set, err := s.eval(n.node)
+		set.negated = !set.negated
+		return set, err
+	case andNode:
+		left, right, err := s.evalPair(n.left, n.right)
+		switch {
+		case err != nil:
+			return postingSet{}, err
+		case !left.negated && !right.negated:
+			return postingSet{units: intersect(left.units, right.units)}, nil
+		case !left.negated:
+			return postingSet{units: difference(left.units, right.units)}, nil
+		case !right.negated:
+			return postingSet{units: difference(right.units, left.units)}, nil
+		default:
+			return postingSet{units: union(left.units, right.units), negated: true}, nil
+		}
+	case orNode:
+		left, right, err := s.evalPair(n.left, n.right)
+		switch {
+		case err != nil:
+			return postingSet{}, err
+		case !left.negated && !right.negated:
+			return postingSet{units: union(left.units, right.units)}, nil
+		case left.negated && right.negated:
+			return postingSet{units: intersect(left.units, right.units), negated: true}, nil
+		case left.negated:
+			return postingSet{units: difference(left.units, right.units), negated: true}, nil
+		default:
+			return postingSet{units: difference(right.units, left.units), negated: true}, nil
+		}
+	}
+	return postingSet{}, fmt.Errorf("unknown query node %T", n)
+}
+
+func (s *Searcher) evalPair(left, right node) (postingSet, postingSet, error) {
+	l, err := s.eval(left)
+	if err != nil {
+		return l, postingSet{}, err
+	}
+	r, err := s.eval(right)
+	return l, r, err
+}
+
+// match returns the lines holding all terms of a match, whose text must
+// hold the text of the match unless it is a single term
+func (s *Searcher) match(n matchNode) ([]uint32, error) {
+	var units []uint32
+	for i, term := range n.terms {
+		postings, err := s.index.Postings(term)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			units = postings
+		} else {
+			units = intersect(units, postings)
+		}
+		if len(units) == 0 {
+			return nil, nil
+		}
+	}
+	if !n.verify {
+		return units, nil
+	}
+
+	if s.verified += len(units); s.verified > maxVerifiedLines {
+		return nil, fmt.Errorf("%w: its phrases match more than %d lines by their terms", ErrQueryTooBroad, maxVerifiedLines)
+	}
+	texts, err := s.texts(units, maxLineLength)
+	if err != nil {
+		return nil, err
+	}
+	matched := units[:0:0]
+	for i, text := range texts {
+		if strings.Contains(string(appendLower(nil, []byte(text))), n.text) {
+			matched = append(matched, units[i])
+		}
+	}
+	return matched, nil
+}
+
+// Hits returns the hits of lines found by Search
+func (s *Searcher) Hits(units []uint32) ([]Hit, error) {
+	texts, err := s.texts(units, maxHitLength)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, len(units))
+	for i, unit := range units {
+		offset, line, err := s.index.Unit(int(unit))
+		if err != nil {
+			return nil, err
+		}
+		hits[i] = Hit{Line: line, Offset: offset, Text: texts[i]}
+	}
+	return hits, nil
+}
+
+// extent is the bytes of a line of the file
+type extent struct {
+	start, end int64 // end is -1 for the last line
+}
+
+// texts reads the text of lines in increasing order, each up to limit bytes
+func (s *Searcher) texts(units []uint32, limit int) ([]string, error) {
+	extents := make([]extent, len(units))
+	for i, unit := range units {
+		start, _, err := s.index.Unit(int(unit))
+		if err != nil {
+			return nil, err
+		}
+		end := int64(-1)
+		if int(unit)+1 < s.index.Units {
+			if end, _, err = s.index.Unit(int(unit) + 1); err != nil {
+				return nil, err
+			}
+		}
+		extents[i] = extent{start: start, end: end}
+	}
+	return readExtents(s.path, extents, limit)
+}
+
+// readExtents reads the text of ascending extents of a file, without the
+// separators ending them. Compressed files are read from their start, as
+// offsets count decompressed bytes.
+func readExtents(path string, extents []extent, limit int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	header = header[:n]
+
+	var stream io.Reader
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		stream = reader
+	case bytes.HasPrefix(header, []byte("BZh")):
+		stream = bzip2.NewReader(file)
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, fmt.Errorf("%w: zstd", ErrUnsupportedCompression)
+	}
+
+	texts := make([]string, len(extents))
+	buf := make([]byte, limit)
+	var position int64
+	var reader *bufio.Reader
+	if stream != nil {
+		reader = bufio.NewReader(stream)
+	}
+	for i, e := range extents {
+		size := int64(limit)
+		if e.end >= 0 {
+			size = min(size, e.end-e.start)
+		}
+
+		var n int
+		if reader == nil {
+			n, err = file.ReadAt(buf[:size], e.start)
+		} else {
+			if _, err = reader.Discard(int(e.start - position)); err == nil {
+				n, err = io.ReadFull(reader, buf[:size])
+				position = e.start + int64(n)
+			}
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+
+		text := buf[:n]
+		if e.end < 0 {
+			// The last line ends at the end of the file or of its line
+			if end := bytes.IndexByte(text, '\n'); end >= 0 {
+				text = text[:end]
+			}
+		}
+		texts[i] = string(bytes.TrimRight(text, " \t\r\n,"))
+	}
+	return texts, nil
+}
+
+// intersect returns the lines in both a and b
+func intersect(a, b []uint32) []uint32 {
+	var result []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// union returns the lines in a or b
+func union(a, b []uint32) []uint32 {
+	result := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// difference returns the lines in a but not in b
+func difference(a, b []uint32) []uint32 {
+	var result []uint32
+	j := 0
+	for _, unit := range a {
+		j += sort.Search(len(b)-j, func(k int) bool { return b[j+k] >= unit })
+		if j >= len(b) || b[j] != unit {
+			result = append(result, unit)
+		}
+	}
+	return result
+}
@@ -48,9 +48,128 @@ type ProgressMessage struct {
 
 type ResultMessage struct {
 	ClientID   string `json:"client_id,omitempty"`
+	JobID      string `json:"job_id,omitempty"`
 	FilePath   string `json:"file_path"`
 	ErrorCount int    `json:"error_count"`
 	WarnCount  int    `json:"warn_count"`
+
+	Levels            map[string]int `json:"levels,omitempty"`
+	TotalLines        int            `json:"total_lines"`
+	TotalBytes        int64          `json:"total_bytes"`
+	MalformedLines    int            `json:"malformed_lines"`
+	OversizedLines    int            `json:"oversized_lines"`
+	LongestLine       int            `json:"longest_line"`
+	Entries           int            `json:"entries"`
+	ContinuationLines int            `json:"continuation_lines"`
+	FirstTimestamp    *time.Time     `json:"first_timestamp,omitempty"`
+	LastTimestamp     *time.Time     `json:"last_timestamp,omitempty"`
+
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	Partial bool   `json:"partial,omitempty"`
+
+	// Status is failed, with the reason in Error and ErrorCategory, for files
+	// that could not be processed
+	Status        string `json:"status,omitempty"`
+	Error         string `json:"error,omitempty"`
+	ErrorCategory string `json:"error_category,omitempty"`
+
+	// Follow is delta or cumulative for the live results of a followed file
+	Follow string `json:"follow,omitempty"`
+
+	// Format is the format the file was read in, Columns the columns the
+	// entries of CSV files were read from
+	Format  string            `json:"format,omitempty"`
+	Columns map[string]string `json:"columns,omitempty"`
+
+	// Excluded counts the lines excluded by the filters of the request
+	Excluded map[string]int `json:"excluded,omitempty"`
+
+	// Indexed is set once the file was indexed for search, IndexError tells
+	// why an index requested for it is missing
+	Indexed    bool   `json:"indexed,omitempty"`
+	IndexError string `json:"index_error,omitempty"`
+
+	Timeline   *TimelineMessage   `json:"timeline,omitempty"`
+	Templates  []TemplateMessage  `json:"templates,omitempty"`
+	Exceptions []ExceptionMessage `json:"exceptions,omitempty"`
+	Samples    []SampleMessage    `json:"samples,omitempty"`
+	Anomalies  []AnomalyMessage   `json:"anomalies,omitempty"`
+}
+
+// TimelineMessage holds per level line counts in fixed size time buckets
+type TimelineMessage struct {
+	BucketSize string `json:"bucket_size"`
+	Buckets    []struct {
+		Start  time.Time      `json:"start"`
+		Counts map[string]int `json:"counts"`
+	} `json:"buckets"`
+}
+
+// TemplateMessage is a recurring message pattern found by the log processor
+type TemplateMessage struct {
+	Level    string `json:"level"`
+	Template string `json:"template"`
+	Count    int    `json:"count"`
+	Example  string `json:"example"`
+}
+
+// ExceptionMessage counts the stack traces of one exception type and top
+// frame found by the log processor
+type ExceptionMessage struct {
+	Type     string         `json:"type"`
+	TopFrame string         `json:"top_frame"`
+	Count    int            `json:"count"`
+	Levels   map[string]int `json:"levels"`
+	Message  string         `json:"message"`
+	Frames   []string       `json:"frames"`
+}
+
+// SampleMessage is a line the log processor kept as an example of its level
+type SampleMessage struct {
+	Level  string `json:"level"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+	Random bool   `json:"random,omitempty"`
+}
+
+// AnomalyMessage is a period whose error or warn rate the log processor found
+// sharply above its rolling baseline
+type AnomalyMessage struct {
+	Level    string    `json:"level"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Lines    int       `json:"lines"`
+	Count    int       `json:"count"`
+	Observed float64   `json:"observed"`
+	Expected float64   `json:"expected"`
+	Score    float64   `json:"score"`
+	Severity string    `json:"severity"`
+}
+
+// JobSummaryMessage sums up a processing job once the log processor ends it
+type JobSummaryMessage struct {
+	JobID    string           `json:"job_id"`
+	ClientID string           `json:"client_id,omitempty"`
+	Status   string           `json:"status"`
+	Error    string           `json:"error,omitempty"`
+	Files    []models.JobFile `json:"files"`
+
+	TotalLines     int            `json:"total_lines"`
+	TotalBytes     int64          `json:"total_bytes"`
+	ErrorCount     int            `json:"error_count"`
+	WarnCount      int            `json:"warn_count"`
+	Levels         map[string]int `json:"levels,omitempty"`
+	Entries        int            `json:"entries"`
+	MalformedLines int            `json:"malformed_lines"`
+
+	Resumed        bool      `json:"resumed,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	DurationMs     int64     `json:"duration_ms"`
+	LinesPerSecond float64   `json:"lines_per_second"`
+	BytesPerSecond float64   `json:"bytes_per_second"`
 }
 
 func NewManager(redisClient *redis.Client, db *gorm.DB) *Manager {
@@ -196,7 +315,7 @@ func (m *Manager) readPump(client *Client) {
 
 func (m *Manager) subscribeToRedis(cfg *config.Config, clientID string) {
 	ctx := context.Background()
-	pubsub := m.redis.Subscribe(ctx, cfg.ProgressChannel, cfg.ResultChannel)
+	pubsub := m.redis.Subscribe(ctx, cfg.ProgressChannel, cfg.ResultChannel, cfg.JobChannel)
 	defer pubsub.Close()
 
 	for {
@@ -243,12 +362,61 @@ func (m *Manager) subscribeToRedis(cfg *config.Config, clientID string) {
 				continue
 			}
 
+			// Live results of followed files go to the client, only the
+			// cumulative ones are stored
+			if resultMsg.Follow != "" && resultMsg.ClientID == clientID {
+				m.broadcast <- []byte(msg.Payload)
+			}
+			if resultMsg.Follow == "delta" {
+				continue
+			}
+
 			fileResult := models.FileResult{
-				FileName:   filepath.Base(resultMsg.FilePath),
-				ClientID:   clientID,
-				Status:     "completed",
-				ErrorCount: &resultMsg.ErrorCount,
-				WarnCount:  &resultMsg.WarnCount,
+				FileName:          resultFileName(&resultMsg),
+				Archive:           resultMsg.Archive,
+				ClientID:          clientID,
+				JobID:             resultMsg.JobID,
+				Status:            "completed",
+				Format:            resultMsg.Format,
+				Columns:           resultMsg.Columns,
+				Excluded:          resultMsg.Excluded,
+				Indexed:           resultMsg.Indexed,
+				IndexError:        resultMsg.IndexError,
+				ErrorCount:        &resultMsg.ErrorCount,
+				WarnCount:         &resultMsg.WarnCount,
+				Levels:            resultMsg.Levels,
+				TotalLines:        &resultMsg.TotalLines,
+				TotalBytes:        &resultMsg.TotalBytes,
+				MalformedLines:    &resultMsg.MalformedLines,
+				OversizedLines:    &resultMsg.OversizedLines,
+				LongestLine:       &resultMsg.LongestLine,
+				Entries:           &resultMsg.Entries,
+				ContinuationLines: &resultMsg.ContinuationLines,
+				FirstTimestamp:    resultMsg.FirstTimestamp,
+				LastTimestamp:     resultMsg.LastTimestamp,
+			}
+
+			// Partial results come from processing that was cancelled midway
+			if resultMsg.Partial {
+				fileResult.Status = "cancelled"
+			}
+
+			// Followed files are following until they are stopped
+			if resultMsg.Follow != "" && resultMsg.Status != "" {
+				fileResult.Status = resultMsg.Status
+			}
+
+			// Files that could not be processed have no counts, only the reason
+			if resultMsg.ErrorCategory != "" {
+				fileResult = models.FileResult{
+					FileName:      fileResult.FileName,
+					Archive:       fileResult.Archive,
+					ClientID:      clientID,
+					JobID:         resultMsg.JobID,
+					Status:        resultMsg.Status,
+					Error:         resultMsg.Error,
+					ErrorCategory: resultMsg.ErrorCategory,
+				}
 			}
 
 			if resultMsg.ClientID != clientID {
@@ -264,7 +432,234 @@ func (m *Manager) subscribeToRedis(cfg *config.Config, clientID string) {
 				UpdateAll: true,
 			}).Create(&fileResult).Error; err != nil {
 				log.Printf("Error storing failed result in database: %v", err)
+				continue
+			}
+
+			// Failed results have no details, those of an earlier result of
+			// the file they replace are removed
+			details := &resultMsg
+			if fileResult.Status == "failed" || fileResult.ErrorCategory != "" {
+				details = &ResultMessage{}
+			}
+			if err := m.storeDetails(fileResult.ID, details); err != nil {
+				log.Printf("Error storing result details in database: %v", err)
+			}
+
+		} else if msg.Channel == cfg.JobChannel {
+			var summaryMsg JobSummaryMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &summaryMsg); err != nil {
+				log.Printf("Error unmarshaling job summary message: %v", err)
+				continue
+			}
+
+			// Every client subscribes, only the one that asked for the job
+			// is told and stores it
+			if summaryMsg.ClientID != clientID {
+				continue
 			}
+			log.Printf("Job summary for client %s: Job: %s, Status: %s, Files: %d",
+				clientID, summaryMsg.JobID, summaryMsg.Status, len(summaryMsg.Files))
+			m.broadcast <- []byte(msg.Payload)
+
+			if err := m.storeJob(&summaryMsg); err != nil {
+				log.Printf("Error storing job summary in database: %v", err)
+			}
+		}
+	}
+}
+
+// storeJob completes the job record of a summary, creating it if the job was
+// not requested through the backend
+func (m *Manager) storeJob(summaryMsg *JobSummaryMessage) error {
+	fileNames := make([]string, len(summaryMsg.Files))
+	for i, file := range summaryMsg.Files {
+		fileNames[i] = file.FileName
+	}
+	job := models.Job{
+		JobID:          summaryMsg.JobID,
+		ClientID:       summaryMsg.ClientID,
+		Status:         summaryMsg.Status,
+		Error:          summaryMsg.Error,
+		FileNames:      fileNames,
+		Files:          summaryMsg.Files,
+		TotalLines:     &summaryMsg.TotalLines,
+		TotalBytes:     &summaryMsg.TotalBytes,
+		ErrorCount:     &summaryMsg.ErrorCount,
+		WarnCount:      &summaryMsg.WarnCount,
+		Levels:         summaryMsg.Levels,
+		Entries:        &summaryMsg.Entries,
+		MalformedLines: &summaryMsg.MalformedLines,
+		Resumed:        summaryMsg.Resumed,
+		StartedAt:      &summaryMsg.StartedAt,
+		FinishedAt:     &summaryMsg.FinishedAt,
+		DurationMs:     &summaryMsg.DurationMs,
+		LinesPerSecond: &summaryMsg.LinesPerSecond,
+		BytesPerSecond: &summaryMsg.BytesPerSecond,
+	}
+	// A job that failed before any file was processed keeps the file names
+	// it was requested with
+	columns := []string{"status", "error", "files", "total_lines", "total_bytes", "error_count", "warn_count", "levels",
+		"entries", "malformed_lines", "resumed", "started_at", "finished_at", "duration_ms", "lines_per_second", "bytes_per_second", "updated_at"}
+	if len(fileNames) > 0 {
+		columns = append(columns, "file_names")
+	}
+	return m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&job).Error
+}
+
+// resultFileName names a result after its file, or after the archive and path
+// of an archive member
+func resultFileName(resultMsg *ResultMessage) string {
+	if resultMsg.Member != "" {
+		return resultMsg.Archive + "/" + resultMsg.Member
+	}
+	return filepath.Base(resultMsg.FilePath)
+}
+
+// storeDetails replaces the stored timeline, templates, exceptions, samples and
+// anomalies of a file result
+func (m *Manager) storeDetails(fileResultID uint, resultMsg *ResultMessage) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := storeTimeline(tx, fileResultID, resultMsg.Timeline); err != nil {
+			return fmt.Errorf("timeline: %v", err)
+		}
+		if err := storeTemplates(tx, fileResultID, resultMsg.Templates); err != nil {
+			return fmt.Errorf("templates: %v", err)
+		}
+		if err := storeExceptions(tx, fileResultID, resultMsg.Exceptions); err != nil {
+			return fmt.Errorf("exceptions: %v", err)
+		}
+		if err := storeSamples(tx, fileResultID, resultMsg.Samples); err != nil {
+			return fmt.Errorf("samples: %v", err)
+		}
+		if err := storeAnomalies(tx, fileResultID, resultMsg.Anomalies); err != nil {
+			return fmt.Errorf("anomalies: %v", err)
+		}
+		return nil
+	})
+}
+
+func storeTimeline(tx *gorm.DB, fileResultID uint, timeline *TimelineMessage) error {
+	if err := tx.Where("file_result_id = ?", fileResultID).Delete(&models.TimelineBucket{}).Error; err != nil {
+		return err
+	}
+	if timeline == nil {
+		return nil
+	}
+
+	bucketSize, err := time.ParseDuration(timeline.BucketSize)
+	if err != nil {
+		return fmt.Errorf("invalid bucket size %q: %v", timeline.BucketSize, err)
+	}
+
+	rows := make([]models.TimelineBucket, 0)
+	for _, bucket := range timeline.Buckets {
+		for level, count := range bucket.Counts {
+			rows = append(rows, models.TimelineBucket{
+				FileResultID:  fileResultID,
+				BucketStart:   bucket.Start,
+				BucketSeconds: int64(bucketSize / time.Second),
+				Level:         level,
+				Count:         count,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func storeTemplates(tx *gorm.DB, fileResultID uint, templates []TemplateMessage) error {
+	if err := tx.Where("file_result_id = ?", fileResultID).Delete(&models.LogTemplate{}).Error; err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	rows := make([]models.LogTemplate, len(templates))
+	for i, template := range templates {
+		rows[i] = models.LogTemplate{
+			FileResultID: fileResultID,
+			Level:        template.Level,
+			Template:     template.Template,
+			Count:        template.Count,
+			Example:      template.Example,
+		}
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func storeExceptions(tx *gorm.DB, fileResultID uint, exceptions []ExceptionMessage) error {
+	if err := tx.Where("file_result_id = ?", fileResultID).Delete(&models.LogException{}).Error; err != nil {
+		return err
+	}
+	if len(exceptions) == 0 {
+		return nil
+	}
+
+	rows := make([]models.LogException, len(exceptions))
+	for i, exception := range exceptions {
+		rows[i] = models.LogException{
+			FileResultID: fileResultID,
+			Type:         exception.Type,
+			TopFrame:     exception.TopFrame,
+			Count:        exception.Count,
+			Levels:       exception.Levels,
+			Message:      exception.Message,
+			Frames:       exception.Frames,
+		}
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func storeSamples(tx *gorm.DB, fileResultID uint, samples []SampleMessage) error {
+	if err := tx.Where("file_result_id = ?", fileResultID).Delete(&models.LogSample{}).Error; err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	rows := make([]models.LogSample, len(samples))
+	for i, sample := range samples {
+		rows[i] = models.LogSample{
+			FileResultID: fileResultID,
+			Level:        sample.Level,
+			Line:         sample.Line,
+			Offset:       sample.Offset,
+			Text:         sample.Text,
+			Random:       sample.Random,
+		}
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func storeAnomalies(tx *gorm.DB, fileResultID uint, anomalies []AnomalyMessage) error {
+	if err := tx.Where("file_result_id = ?", fileResultID).Delete(&models.LogAnomaly{}).Error; err != nil {
+		return err
+	}
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	rows := make([]models.LogAnomaly, len(anomalies))
+	for i, anomaly := range anomalies {
+		rows[i] = models.LogAnomaly{
+			FileResultID: fileResultID,
+			Level:        anomaly.Level,
+			StartTime:    anomaly.Start,
+			EndTime:      anomaly.End,
+			Lines:        anomaly.Lines,
+			Count:        anomaly.Count,
+			Observed:     anomaly.Observed,
+			Expected:     anomaly.Expected,
+			Score:        anomaly.Score,
+			Severity:     anomaly.Severity,
 		}
 	}
+	return tx.CreateInBatches(rows, 500).Error
 }
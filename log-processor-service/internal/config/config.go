@@ -2,15 +2,88 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultEntryStartPattern matches lines starting with a date, a bracketed
+// timestamp, a syslog timestamp, a level or a Go panic, so the frames of stack
+// traces and other indented or unprefixed lines continue the entry before them
+const DefaultEntryStartPattern = `^(\[\d|\d{4}-\d{2}-\d{2}|[A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}|(?i:trace|debug|info|warn|warning|error|fatal|critical)\b|panic: |fatal error: )`
+
 type Config struct {
 	RedisAddress      string
 	ProcessingChannel string
 	ProgressChannel   string
 	ResultChannel     string
+	FollowChannel     string
+	DetectChannel     string
+	JobChannel        string
 	NumWorkers        int
 	UploadDir         string
+
+	// Line classification
+	Classifier          string
+	ClassifierField     int
+	ClassifierRulesFile string
+
+	// Lines matching the pattern start a log entry of the generator's
+	// format, others continue the entry before them. The other line
+	// formats have patterns of their own; an empty pattern makes every line
+	// an entry in all of them.
+	EntryStartPattern string
+
+	// Size of the buckets used for per file timelines
+	TimelineBucket time.Duration
+
+	// Detection of timeline buckets whose error or warn rate rises sharply
+	// above a rolling baseline: the method (ewma, mad or none), the score
+	// in deviations above which a bucket is anomalous, the number of
+	// buckets in the baseline and the lines a bucket needs to be compared
+	AnomalyMethod    string
+	AnomalyThreshold float64
+	AnomalyWindow    int
+	AnomalyMinLines  int
+
+	// Number of message templates reported per level
+	TemplateTopN int
+
+	// Paths of the fields the level, timestamp and message of JSON logs are
+	// read from, the first present of each is used
+	JSONLevelFields     []string
+	JSONTimestampFields []string
+	JSONMessageFields   []string
+
+	// JSON payload fields to aggregate ("*" for every top level key)
+	AggregateFields []string
+	FieldTopK       int
+
+	// Number of first and of random lines sampled per level, 0 disables
+	// sampling
+	SampleLines int
+
+	// Files above the threshold are split into chunks processed in parallel
+	ChunkThreshold int64
+	ChunkSize      int64
+
+	// Lines longer than this are truncated and counted as oversized
+	MaxLineLength int
+
+	// Limits on the members of zip and tar archives; the size and ratio
+	// limits bound single compressed files too
+	MaxArchiveMembers   int
+	MaxArchiveBytes     int64
+	MaxCompressionRatio int
+
+	// Interval between checkpoints of the files being processed
+	CheckpointInterval time.Duration
+
+	// Interval between the results of followed files
+	FollowInterval time.Duration
+
+	// Number of lines sampled from the head of a file to detect its format
+	DetectSampleLines int
 }
 
 func NewConfig() *Config {
@@ -19,8 +92,49 @@ func NewConfig() *Config {
 		ProcessingChannel: getEnvOrDefault("PROCESSING_CHANNEL", "processing_channel"),
 		ProgressChannel:   getEnvOrDefault("PROGRESS_CHANNEL", "progress_channel"),
 		ResultChannel:     getEnvOrDefault("RESULT_CHANNEL", "result_channel"),
+		FollowChannel:     getEnvOrDefault("FOLLOW_CHANNEL", "follow_channel"),
+		DetectChannel:     getEnvOrDefault("DETECT_CHANNEL", "detect_channel"),
+		JobChannel:        getEnvOrDefault("JOB_CHANNEL", "job_channel"),
 		NumWorkers:        4,
 		UploadDir:         getEnvOrDefault("UPLOAD_DIR", "../uploads"),
+
+		Classifier:          getEnvOrDefault("CLASSIFIER", "word"),
+		ClassifierField:     getEnvIntOrDefault("CLASSIFIER_LEVEL_FIELD", 1),
+		ClassifierRulesFile: getEnvOrDefault("CLASSIFIER_RULES_FILE", ""),
+
+		EntryStartPattern: getEnvSetOrDefault("ENTRY_START_PATTERN", DefaultEntryStartPattern),
+
+		TimelineBucket: getEnvDurationOrDefault("TIMELINE_BUCKET", time.Minute),
+		TemplateTopN:   getEnvIntOrDefault("TEMPLATE_TOP_N", 10),
+
+		AnomalyMethod:    getEnvOrDefault("ANOMALY_METHOD", "ewma"),
+		AnomalyThreshold: getEnvFloatOrDefault("ANOMALY_THRESHOLD", 4),
+		AnomalyWindow:    getEnvIntOrDefault("ANOMALY_WINDOW", 30),
+		AnomalyMinLines:  getEnvIntOrDefault("ANOMALY_MIN_LINES", 20),
+
+		JSONLevelFields:     getEnvListOrDefault("JSON_LEVEL_FIELDS", []string{"level", "severity", "log.level", "lvl", "loglevel"}),
+		JSONTimestampFields: getEnvListOrDefault("JSON_TIMESTAMP_FIELDS", []string{"@timestamp", "timestamp", "time", "ts"}),
+		JSONMessageFields:   getEnvListOrDefault("JSON_MESSAGE_FIELDS", []string{"message", "msg", "@message"}),
+
+		AggregateFields: getEnvListOrDefault("AGGREGATE_FIELDS", []string{"userId", "ip"}),
+		FieldTopK:       getEnvIntOrDefault("FIELD_TOP_K", 10),
+
+		SampleLines: getEnvIntOrDefault("SAMPLE_LINES", 5),
+
+		ChunkThreshold: int64(getEnvIntOrDefault("CHUNK_THRESHOLD_MB", 64)) * 1024 * 1024,
+		ChunkSize:      int64(getEnvIntOrDefault("CHUNK_SIZE_MB", 32)) * 1024 * 1024,
+
+		MaxLineLength: getEnvIntOrDefault("MAX_LINE_LENGTH_KB", 64) * 1024,
+
+		MaxArchiveMembers:   getEnvIntOrDefault("MAX_ARCHIVE_MEMBERS", 1000),
+		MaxArchiveBytes:     int64(getEnvIntOrDefault("MAX_ARCHIVE_SIZE_MB", 4096)) * 1024 * 1024,
+		MaxCompressionRatio: getEnvIntOrDefault("MAX_COMPRESSION_RATIO", 100),
+
+		CheckpointInterval: getEnvDurationOrDefault("CHECKPOINT_INTERVAL", 30*time.Second),
+
+		FollowInterval: getEnvDurationOrDefault("FOLLOW_INTERVAL", 5*time.Second),
+
+		DetectSampleLines: getEnvIntOrDefault("DETECT_SAMPLE_LINES", 200),
 	}
 }
 
@@ -30,3 +144,53 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvSetOrDefault is like getEnvOrDefault but keeps a value set to empty
+func getEnvSetOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
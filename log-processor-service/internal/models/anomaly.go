@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Severities of anomalies, by how far their rate departs from the baseline
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Anomaly is a run of timeline buckets whose rate of a level, ERROR or WARN,
+// rose sharply above the rolling baseline of the buckets before them
+type Anomaly struct {
+	Level string    `json:"level"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Lines is the number of lines in the buckets, Count those of the level
+	Lines int `json:"lines"`
+	Count int `json:"count"`
+
+	// Observed is the rate of the level in the buckets, Expected the rate
+	// of the baseline and Score the largest departure from it in deviations
+	Observed float64 `json:"observed"`
+	Expected float64 `json:"expected"`
+	Score    float64 `json:"score"`
+	Severity string  `json:"severity"`
+}
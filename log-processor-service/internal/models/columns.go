@@ -0,0 +1,9 @@
+package models
+
+// ColumnMapping names the columns of a CSV log holding the timestamp, level
+// and message of each entry. Columns left empty are detected from the header.
+type ColumnMapping struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
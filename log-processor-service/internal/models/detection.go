@@ -0,0 +1,27 @@
+package models
+
+// Detection is the format of a file detected from a sample of its head
+type Detection struct {
+	FileName string `json:"file_name"`
+
+	// Format is the format that parsed the sample best, text if none parsed
+	// any of it
+	Format string `json:"format"`
+
+	// SuccessRate is the share of sampled entries the format parsed, and
+	// Confidence that rate less half the rate of the runner-up, so samples
+	// several formats parse equally well get a low confidence
+	SuccessRate float64 `json:"success_rate"`
+	Confidence  float64 `json:"confidence"`
+
+	// Scores is the success rate of every format
+	Scores map[string]float64 `json:"scores"`
+
+	// SampledLines is the number of lines sampled, Sample the first entries
+	// parsed in the detected format
+	SampledLines int        `json:"sampled_lines"`
+	Sample       []LogEntry `json:"sample,omitempty"`
+
+	// Compression is the format the sample was decompressed from, if any
+	Compression string `json:"compression,omitempty"`
+}
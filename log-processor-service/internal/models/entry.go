@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LogEntry is a single structured log line of the form
+// "[2025-01-02T15:04:05Z] LEVEL message {"key":"value"}"
+type LogEntry struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	Payload   map[string]any `json:"payload,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// ExceptionStats counts the entries whose stack trace has one exception type
+// thrown at one top frame
+type ExceptionStats struct {
+	Type     string         `json:"type"`
+	TopFrame string         `json:"top_frame,omitempty"`
+	Count    int            `json:"count"`
+	Levels   map[string]int `json:"levels,omitempty"`
+
+	// Message and Frames are those of the first trace seen, top frame first
+	Message string   `json:"message,omitempty"`
+	Frames  []string `json:"frames,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// FieldStats summarises the values of one field of the JSON payloads in a file
+type FieldStats struct {
+	Field    string `json:"field"`
+	Count    int    `json:"count"`
+	Distinct int    `json:"distinct"`
+	// Truncated is set when there were too many distinct values to track them all
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Top are the most frequent values, TopErrors the values with most ERROR and FATAL lines
+	Top       []FieldValue `json:"top"`
+	TopErrors []FieldValue `json:"top_errors,omitempty"`
+}
+
+// FieldValue is the number of lines per level carrying one field value
+type FieldValue struct {
+	Value  string         `json:"value"`
+	Count  int            `json:"count"`
+	Levels map[string]int `json:"levels,omitempty"`
+}
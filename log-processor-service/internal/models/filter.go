@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Filters select the entries of a job that are analysed, entries failing any
+// of them are excluded before counting
+type Filters struct {
+	// From and To bound the timestamps of entries, From inclusive and To
+	// exclusive
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+
+	// Levels are the levels kept
+	Levels []string `json:"levels,omitempty"`
+
+	// Message is a regular expression the message must match
+	Message string `json:"message,omitempty"`
+
+	// Fields maps payload field paths, such as userId or request.ip, to the
+	// value they must have
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// Names of the filters, by which excluded lines are counted
+const (
+	FilterTime    = "time"
+	FilterLevel   = "level"
+	FilterMessage = "message"
+	FilterFields  = "fields"
+)
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatusCompletedWithErrors is the status of a job some of whose files failed
+const StatusCompletedWithErrors = "completed_with_errors"
+
+// JobSummary sums up the files of a processing job once it ends. Archives
+// count as one file, their members are summed up in their total.
+type JobSummary struct {
+	JobID    string `json:"job_id"`
+	ClientID string `json:"client_id,omitempty"`
+
+	// Status is completed if every file was, failed if none was and
+	// completed_with_errors otherwise, or cancelled if the job was cut short
+	// to be resumed on restart. Error tells why a job failed before any
+	// file was processed.
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Files  []JobFile `json:"files"`
+
+	TotalLines     int            `json:"total_lines"`
+	TotalBytes     int64          `json:"total_bytes"`
+	ErrorCount     int            `json:"error_count"`
+	WarnCount      int            `json:"warn_count"`
+	Levels         map[string]int `json:"levels,omitempty"`
+	Entries        int            `json:"entries"`
+	MalformedLines int            `json:"malformed_lines"`
+
+	// StartedAt and FinishedAt bound the run of the job, which for a
+	// resumed job is the run after the restart. Throughput is the lines
+	// and bytes of the files per second of the run, leaving out those
+	// restored from the checkpoints of the run before the restart.
+	Resumed        bool      `json:"resumed,omitempty"`
+	RestoredLines  int       `json:"restored_lines,omitempty"`
+	RestoredBytes  int64     `json:"restored_bytes,omitempty"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	DurationMs     int64     `json:"duration_ms"`
+	LinesPerSecond float64   `json:"lines_per_second"`
+	BytesPerSecond float64   `json:"bytes_per_second"`
+}
+
+// JobFile is the status of a file of a job with its main counts
+type JobFile struct {
+	FileName      string        `json:"file_name"`
+	Status        string        `json:"status"`
+	Error         string        `json:"error,omitempty"`
+	ErrorCategory ErrorCategory `json:"error_category,omitempty"`
+	TotalLines    int           `json:"total_lines"`
+	TotalBytes    int64         `json:"total_bytes"`
+	ErrorCount    int           `json:"error_count"`
+	WarnCount     int           `json:"warn_count"`
+	Members       int           `json:"members,omitempty"`
+}
+
+// NewJobSummary starts the summary of a job run that started at startedAt
+func NewJobSummary(jobID, clientID string, startedAt time.Time) *JobSummary {
+	return &JobSummary{JobID: jobID, ClientID: clientID, StartedAt: startedAt}
+}
+
+// AddFile adds the result of a file of the job, or the total of an archive
+func (j *JobSummary) AddFile(fileName string, result Result) {
+	status := result.Status
+	if status == "" {
+		status = StatusCompleted
+	}
+	j.Files = append(j.Files, JobFile{
+		FileName:      fileName,
+		Status:        status,
+		Error:         result.Error,
+		ErrorCategory: result.ErrorCategory,
+		TotalLines:    result.TotalLines,
+		TotalBytes:    result.TotalBytes,
+		ErrorCount:    result.ErrorCount,
+		WarnCount:     result.WarnCount,
+		Members:       result.Members,
+	})
+	if result.Status == StatusFailed {
+		return
+	}
+
+	j.TotalLines += result.TotalLines
+	j.TotalBytes += result.TotalBytes
+	j.ErrorCount += result.ErrorCount
+	j.WarnCount += result.WarnCount
+	for level, count := range result.Levels {
+		if j.Levels == nil {
+			j.Levels = make(map[string]int)
+		}
+		j.Levels[level] += count
+	}
+	j.Entries += result.Entries
+	j.MalformedLines += result.MalformedLines
+}
+
+// AddRestored adds the lines and bytes of a file of the job restored from
+// checkpoints, which its result counts but the run did not scan
+func (j *JobSummary) AddRestored(lines int, bytes int64) {
+	j.RestoredLines += lines
+	j.RestoredBytes += bytes
+}
+
+// Finish ends the summary at finishedAt, cancelled if the job was cut short
+func (j *JobSummary) Finish(finishedAt time.Time, cancelled bool) {
+	j.FinishedAt = finishedAt
+	duration := finishedAt.Sub(j.StartedAt)
+	j.DurationMs = duration.Milliseconds()
+	if seconds := duration.Seconds(); seconds > 0 {
+		j.LinesPerSecond = float64(j.TotalLines-j.RestoredLines) / seconds
+		j.BytesPerSecond = float64(j.TotalBytes-j.RestoredBytes) / seconds
+	}
+
+	failed := 0
+	for _, file := range j.Files {
+		if file.Status == StatusFailed {
+			failed++
+		}
+	}
+	switch {
+	case cancelled:
+		j.Status = StatusCancelled
+	case j.Error != "":
+		j.Status = StatusFailed
+	case failed == 0:
+		j.Status = StatusCompleted
+	case failed == len(j.Files):
+		j.Status = StatusFailed
+	default:
+		j.Status = StatusCompletedWithErrors
+	}
+}
+
+// String returns a formatted string representation of the summary
+func (j *JobSummary) String() string {
+	return fmt.Sprintf("Job: %s, Status: %s, Files: %d, Lines: %d, Bytes: %d, Error Count: %d, Warn Count: %d, Duration: %dms, Lines/s: %.0f",
+		j.JobID, j.Status, len(j.Files), j.TotalLines, j.TotalBytes, j.ErrorCount, j.WarnCount, j.DurationMs, j.LinesPerSecond)
+}
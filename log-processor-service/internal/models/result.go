@@ -1,23 +1,190 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Result represents the processing result for a single log file
 type Result struct {
 	ClientID   string `json:"client_id,omitempty"`
+	JobID      string `json:"job_id,omitempty"`
 	FilePath   string `json:"file_path"`
 	ErrorCount int    `json:"error_count"`
 	WarnCount  int    `json:"warn_count"`
+
+	// Levels is the number of lines per level (DEBUG, INFO, WARN, ERROR, FATAL)
+	Levels map[string]int `json:"levels,omitempty"`
+
+	// Line and byte totals, TotalBytes counts decompressed bytes for compressed files
+	TotalLines     int        `json:"total_lines"`
+	TotalBytes     int64      `json:"total_bytes"`
+	LongestLine    int        `json:"longest_line"`
+	FirstTimestamp *time.Time `json:"first_timestamp,omitempty"`
+	LastTimestamp  *time.Time `json:"last_timestamp,omitempty"`
+
+	// Entries counts log entries, which span a line and the continuation
+	// lines following it, such as the frames of a stack trace
+	Entries           int `json:"entries"`
+	ContinuationLines int `json:"continuation_lines"`
+
+	// Exceptions are the stack traces found, by exception type and top frame
+	Exceptions []ExceptionStats `json:"exceptions,omitempty"`
+
+	// Timeline counts lines per level over time
+	Timeline *Timeline `json:"timeline,omitempty"`
+
+	// Anomalies are the periods of the timeline whose error or warn rate
+	// rose sharply above its rolling baseline
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+
+	// Templates are the most frequent message patterns per level
+	Templates []Template `json:"templates,omitempty"`
+
+	// Fields aggregates the values of configured JSON payload fields
+	Fields []FieldStats `json:"fields,omitempty"`
+
+	// Samples are the first lines of every level and some picked at random
+	// from the rest, ordered by level and line
+	Samples []Sample `json:"samples,omitempty"`
+
+	// MalformedLines counts lines that could not be parsed into a LogEntry,
+	// including the continuation lines of entries that are not part of a
+	// stack trace, ParseErrors keeps the first few reasons
+	MalformedLines int      `json:"malformed_lines"`
+	ParseErrors    []string `json:"parse_errors,omitempty"`
+
+	// OversizedLines counts lines over the maximum line length, which were
+	// truncated before classification and analysis
+	OversizedLines int `json:"oversized_lines"`
+
+	// Excluded counts the lines of entries excluded by the filters of the
+	// job, by the first filter that excluded them. They are only counted in
+	// the line and byte totals.
+	Excluded map[string]int `json:"excluded,omitempty"`
+
+	// Archive and Member locate a file inside an uploaded archive, Members is
+	// the number of members summed up in the total of an archive
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+	Members int    `json:"members,omitempty"`
+
+	// Indexed is set once the index of the file was saved, IndexError tells
+	// why the index requested for the file is missing
+	Indexed    bool   `json:"indexed,omitempty"`
+	IndexError string `json:"index_error,omitempty"`
+
+	// Partial marks results of files whose processing was cancelled before the
+	// end, counting only the lines scanned until then
+	Partial bool `json:"partial,omitempty"`
+
+	// Status is completed, cancelled for partial results, or failed, in which
+	// case Error and ErrorCategory tell why and no counts are set
+	Status        string        `json:"status,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	ErrorCategory ErrorCategory `json:"error_category,omitempty"`
+
+	// Follow is delta or cumulative for the results of a followed file
+	Follow string `json:"follow,omitempty"`
+
+	// Compression is the format the file was decompressed from, if any
+	Compression string `json:"compression,omitempty"`
+
+	// Format is the format the file was read in, such as text, json or
+	// access. Columns are the columns the entries of CSV files were read from.
+	Format  string         `json:"format,omitempty"`
+	Columns *ColumnMapping `json:"columns,omitempty"`
+
+	// Classifier and Rules describe how lines were assigned a level
+	Classifier string   `json:"classifier,omitempty"`
+	Rules      []string `json:"rules,omitempty"`
+}
+
+// NewFailedResult creates the result of a file that could not be processed
+func NewFailedResult(filePath string, err error, category ErrorCategory) Result {
+	status := StatusFailed
+	if category == ErrorCancelled {
+		status = StatusCancelled
+	}
+	return Result{
+		FilePath:      filePath,
+		Status:        status,
+		Error:         err.Error(),
+		ErrorCategory: category,
+	}
+}
+
+// MarkPartial marks the result of a file whose processing was cancelled
+func (r *Result) MarkPartial() {
+	r.Partial = true
+	r.Status = StatusCancelled
+}
+
+// AddLevel counts a line of the given level
+func (r *Result) AddLevel(level string, count int) {
+	if r.Levels == nil {
+		r.Levels = make(map[string]int)
+	}
+	r.Levels[level] += count
+}
+
+// AddExcluded counts lines excluded by the given filter
+func (r *Result) AddExcluded(filter string, count int) {
+	if r.Excluded == nil {
+		r.Excluded = make(map[string]int)
+	}
+	r.Excluded[filter] += count
+}
+
+// AddTimestamp widens the time span of the result to include t
+func (r *Result) AddTimestamp(t time.Time) {
+	if r.FirstTimestamp == nil || t.Before(*r.FirstTimestamp) {
+		first := t
+		r.FirstTimestamp = &first
+	}
+	if r.LastTimestamp == nil || t.After(*r.LastTimestamp) {
+		last := t
+		r.LastTimestamp = &last
+	}
 }
 
-// Add combines two results
+// Add combines two results, failed results carry no counts and are ignored
 func (r *Result) Add(result Result) {
+	if result.Status == StatusFailed {
+		return
+	}
 	r.ErrorCount += result.ErrorCount
 	r.WarnCount += result.WarnCount
+	for level, count := range result.Levels {
+		r.AddLevel(level, count)
+	}
+	r.TotalLines += result.TotalLines
+	r.TotalBytes += result.TotalBytes
+	r.Entries += result.Entries
+	r.ContinuationLines += result.ContinuationLines
+	r.LongestLine = max(r.LongestLine, result.LongestLine)
+	if result.FirstTimestamp != nil {
+		r.AddTimestamp(*result.FirstTimestamp)
+	}
+	if result.LastTimestamp != nil {
+		r.AddTimestamp(*result.LastTimestamp)
+	}
+	if r.Timeline == nil && result.Timeline != nil {
+		r.Timeline = &Timeline{BucketSize: result.Timeline.BucketSize}
+	}
+	if r.Timeline != nil {
+		r.Timeline.Merge(result.Timeline)
+	}
+	r.MalformedLines += result.MalformedLines
+	r.OversizedLines += result.OversizedLines
+	for filter, count := range result.Excluded {
+		r.AddExcluded(filter, count)
+	}
+	r.Partial = r.Partial || result.Partial
 }
 
 // String returns a formatted string representation of the result
 func (r *Result) String() string {
-	return fmt.Sprintf("File: %s, Error Count: %d, Warn Count: %d",
-		r.FilePath, r.ErrorCount, r.WarnCount)
+	return fmt.Sprintf("File: %s, Lines: %d, Bytes: %d, Levels: %v, Error Count: %d, Warn Count: %d, Malformed Lines: %d, Oversized Lines: %d",
+		r.FilePath, r.TotalLines, r.TotalBytes, r.Levels, r.ErrorCount, r.WarnCount, r.MalformedLines, r.OversizedLines)
 }
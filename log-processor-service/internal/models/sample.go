@@ -0,0 +1,15 @@
+package models
+
+// Sample is a line kept as an example of its level, located by its line
+// number and the byte offset of its start, which count decompressed bytes for
+// compressed files
+type Sample struct {
+	Level  string `json:"level"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`
+
+	// Random marks samples picked at random rather than among the first
+	// lines of their level
+	Random bool `json:"random,omitempty"`
+}
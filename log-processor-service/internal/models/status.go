@@ -0,0 +1,31 @@
+package models
+
+// Statuses of a processed file
+const (
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+	// StatusFollowing is the status of a followed file until following stops
+	StatusFollowing = "following"
+)
+
+// Kinds of results of a followed file
+const (
+	// FollowDelta covers the lines appended since the previous result
+	FollowDelta = "delta"
+	// FollowCumulative covers every line read since following started
+	FollowCumulative = "cumulative"
+)
+
+// ErrorCategory classifies why a file could not be processed
+type ErrorCategory string
+
+const (
+	ErrorNotFound   ErrorCategory = "not_found"
+	ErrorPermission ErrorCategory = "permission"
+	ErrorDecode     ErrorCategory = "decode"
+	ErrorLimit      ErrorCategory = "limit"
+	ErrorCancelled  ErrorCategory = "cancelled"
+	ErrorPanic      ErrorCategory = "panic"
+	ErrorIO         ErrorCategory = "io"
+)
@@ -0,0 +1,10 @@
+package models
+
+// Template is a recurring message pattern with variables masked, e.g.
+// "Request from <IP> took <NUM> ms"
+type Template struct {
+	Level    string `json:"level"`
+	Template string `json:"template"`
+	Count    int    `json:"count"`
+	Example  string `json:"example"`
+}
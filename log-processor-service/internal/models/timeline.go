@@ -0,0 +1,43 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Timeline holds per level line counts in fixed size time buckets
+type Timeline struct {
+	BucketSize string           `json:"bucket_size"`
+	Buckets    []TimelineBucket `json:"buckets"`
+}
+
+// TimelineBucket is the number of lines per level starting at Start
+type TimelineBucket struct {
+	Start  time.Time      `json:"start"`
+	Counts map[string]int `json:"counts"`
+}
+
+// Merge adds the buckets of another timeline with the same bucket size
+func (t *Timeline) Merge(other *Timeline) {
+	if other == nil || other.BucketSize != t.BucketSize {
+		return
+	}
+
+	index := make(map[time.Time]int, len(t.Buckets))
+	for i, bucket := range t.Buckets {
+		index[bucket.Start] = i
+	}
+	for _, bucket := range other.Buckets {
+		i, ok := index[bucket.Start]
+		if !ok {
+			i = len(t.Buckets)
+			index[bucket.Start] = i
+			t.Buckets = append(t.Buckets, TimelineBucket{Start: bucket.Start, Counts: make(map[string]int)})
+		}
+		for level, count := range bucket.Counts {
+			t.Buckets[i].Counts[level] += count
+		}
+	}
+
+	sort.Slice(t.Buckets, func(i, j int) bool { return t.Buckets[i].Start.Before(t.Buckets[j].Start) })
+}
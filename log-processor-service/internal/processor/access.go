@@ -0,0 +1,169 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// accessLogFields are the payload fields of access logs broken down in every
+// result, besides the configured ones
+var accessLogFields = []string{"status", "path"}
+
+// accessTimeLayout is the format of the timestamps of access logs
+const accessTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+var errInvalidAccessLog = errors.New("not a combined or common access log line")
+
+// accessEntryStart matches the client, identity, user and opening bracket of
+// the timestamp starting an access log line
+var accessEntryStart = regexp.MustCompile(`^\S+ \S+ \S+ \[`)
+
+// accessLogParser parses lines of the combined log format of Nginx and Apache,
+// and of the common format, which lacks the referer and user agent:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326 "http://example.com/" "Mozilla/4.08"
+//
+// The level follows the status, ERROR for 5xx, WARN for 4xx and INFO for the
+// others.
+type accessLogParser struct{}
+
+func (accessLogParser) Name() string { return FormatAccess }
+
+func (accessLogParser) EntryStart() *regexp.Regexp { return accessEntryStart }
+
+func (accessLogParser) Parse(line []byte) (models.LogEntry, error) {
+	var entry models.LogEntry
+	rest := bytes.TrimRight(line, "\r")
+
+	var ip, user []byte
+	var ok bool
+	if ip, rest, ok = nextToken(rest); !ok {
+		return entry, errInvalidAccessLog
+	}
+	if _, rest, ok = nextToken(rest); !ok {
+		return entry, errInvalidAccessLog
+	}
+	if user, rest, ok = nextToken(rest); !ok {
+		return entry, errInvalidAccessLog
+	}
+
+	timestamp, rest, ok := bracketed(rest)
+	if !ok {
+		return entry, fmt.Errorf("%w: missing bracketed timestamp", errInvalidAccessLog)
+	}
+	t, err := time.Parse(accessTimeLayout, string(timestamp))
+	if err != nil {
+		return entry, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	entry.Timestamp = t.UTC()
+
+	request, rest, ok := quoted(rest)
+	if !ok {
+		return entry, fmt.Errorf("%w: missing request", errInvalidAccessLog)
+	}
+	statusToken, rest, _ := nextToken(rest)
+	status, err := strconv.Atoi(string(statusToken))
+	if err != nil || status < 100 || status > 999 {
+		return entry, fmt.Errorf("%w: invalid status %q", errInvalidAccessLog, statusToken)
+	}
+	size, rest, _ := nextToken(rest)
+
+	payload := map[string]any{
+		"ip":     string(ip),
+		"status": json.Number(strconv.Itoa(status)),
+	}
+	if string(user) != "-" {
+		payload["user"] = string(user)
+	}
+	if n, err := strconv.ParseInt(string(size), 10, 64); err == nil {
+		payload["bytes"] = json.Number(strconv.FormatInt(n, 10))
+	}
+
+	// A request that is not "METHOD target PROTOCOL", such as garbage sent to
+	// the server, is kept whole as the message
+	entry.Message = string(request)
+	if method, target, ok := strings.Cut(string(request), " "); ok {
+		target, protocol, _ := strings.Cut(target, " ")
+		path, query, _ := strings.Cut(target, "?")
+		payload["method"] = method
+		payload["path"] = path
+		if query != "" {
+			payload["query"] = query
+		}
+		if protocol != "" {
+			payload["protocol"] = protocol
+		}
+		entry.Message = method + " " + path
+	}
+
+	if referer, rest, ok := quoted(rest); ok {
+		if string(referer) != "-" {
+			payload["referer"] = string(referer)
+		}
+		if userAgent, _, ok := quoted(rest); ok && string(userAgent) != "-" {
+			payload["user_agent"] = string(userAgent)
+		}
+	}
+
+	switch {
+	case status >= 500:
+		entry.Level = LevelError
+	case status >= 400:
+		entry.Level = LevelWarn
+	default:
+		entry.Level = LevelInfo
+	}
+	entry.Payload = payload
+	return entry, nil
+}
+
+// nextToken splits off the token up to the next space
+func nextToken(b []byte) (token, rest []byte, ok bool) {
+	b = bytes.TrimLeft(b, " ")
+	if len(b) == 0 {
+		return nil, nil, false
+	}
+	if end := bytes.IndexByte(b, ' '); end >= 0 {
+		return b[:end], b[end+1:], true
+	}
+	return b, nil, true
+}
+
+// bracketed splits off a token enclosed in square brackets
+func bracketed(b []byte) (token, rest []byte, ok bool) {
+	b = bytes.TrimLeft(b, " ")
+	if len(b) == 0 || b[0] != '[' {
+		return nil, b, false
+	}
+	end := bytes.IndexByte(b, ']')
+	if end < 0 {
+		return nil, b, false
+	}
+	return b[1:end], b[end+1:], true
+}
+
+// quoted splits off a token enclosed in double quotes, in which quotes are
+// escaped by a backslash. The escapes are kept.
+func quoted(b []byte) (token, rest []byte, ok bool) {
+	b = bytes.TrimLeft(b, " ")
+	if len(b) == 0 || b[0] != '"' {
+		return nil, b, false
+	}
+	for i := 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return b[1:i], b[i+1:], true
+		}
+	}
+	return nil, b, false
+}
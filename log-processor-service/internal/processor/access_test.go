@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		level   string
+		message string
+		payload map[string]any
+	}{
+		{
+			name:    "combined",
+			line:    `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=2 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			level:   LevelInfo,
+			message: "GET /apache_pb.gif",
+			payload: map[string]any{
+				"ip":         "127.0.0.1",
+				"user":       "frank",
+				"status":     json.Number("200"),
+				"bytes":      json.Number("2326"),
+				"method":     "GET",
+				"path":       "/apache_pb.gif",
+				"query":      "size=2",
+				"protocol":   "HTTP/1.0",
+				"referer":    "http://www.example.com/start.html",
+				"user_agent": "Mozilla/4.08 [en] (Win98; I ;Nav)",
+			},
+		},
+		{
+			name:    "common",
+			line:    "10.0.0.7 - - [10/Oct/2000:13:55:36 -0700] \"POST /login HTTP/1.1\" 404 -\r",
+			level:   LevelWarn,
+			message: "POST /login",
+			payload: map[string]any{
+				"ip":       "10.0.0.7",
+				"status":   json.Number("404"),
+				"method":   "POST",
+				"path":     "/login",
+				"protocol": "HTTP/1.1",
+			},
+		},
+		{
+			name:    "garbage request",
+			line:    `192.168.1.9 - - [10/Oct/2000:13:55:36 -0700] "\x16\x03\x01" 503 0 "-" "-"`,
+			level:   LevelError,
+			message: `\x16\x03\x01`,
+			payload: map[string]any{
+				"ip":     "192.168.1.9",
+				"status": json.Number("503"),
+				"bytes":  json.Number("0"),
+			},
+		},
+	}
+	want := time.Date(2000, time.October, 10, 20, 55, 36, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := accessLogParser{}.Parse([]byte(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Level != tt.level || entry.Message != tt.message {
+				t.Errorf("got level %q, message %q, want %q, %q", entry.Level, entry.Message, tt.level, tt.message)
+			}
+			if !entry.Timestamp.Equal(want) {
+				t.Errorf("got timestamp %v, want %v", entry.Timestamp, want)
+			}
+			if !reflect.DeepEqual(entry.Payload, tt.payload) {
+				t.Errorf("got payload %v, want %v", entry.Payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestAccessLogParserRejects(t *testing.T) {
+	for _, line := range []string{
+		`127.0.0.1 - - 10/Oct/2000:13:55:36 -0700 "GET / HTTP/1.0" 200 1`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36] "GET / HTTP/1.0" 200 1`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] GET / 200 1`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" OK 1`,
+		"2025-04-01T10:00:00Z INFO started",
+	} {
+		_, err := accessLogParser{}.Parse([]byte(line))
+		if !errors.Is(err, errInvalidAccessLog) && (err == nil || !strings.HasPrefix(err.Error(), "invalid timestamp")) {
+			t.Errorf("Parse(%q) = %v, want an access log error", line, err)
+		}
+	}
+}
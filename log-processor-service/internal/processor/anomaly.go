@@ -0,0 +1,250 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// Names of the anomaly detection methods
+const (
+	AnomalyEWMA = "ewma"
+	AnomalyMAD  = "mad"
+	AnomalyNone = "none"
+)
+
+const (
+	// defaultAnomalyThreshold and defaultAnomalyWindow replace a threshold
+	// or window that is not set
+	defaultAnomalyThreshold = 4
+	defaultAnomalyWindow    = 30
+
+	// minBaselineBuckets is the number of buckets a baseline needs before
+	// buckets are compared to it
+	minBaselineBuckets = 5
+	// minRateDeviation keeps a flat baseline from making any change an
+	// anomaly
+	minRateDeviation = 0.001
+	// madScale turns a median absolute deviation into a standard deviation
+	// of normally distributed rates
+	madScale = 1.4826
+)
+
+// anomalyLevels are the levels whose rates are watched
+var anomalyLevels = []string{LevelError, LevelWarn}
+
+// rateBaseline is the rolling expectation of the rate of a level
+type rateBaseline interface {
+	// expect returns the expected rate and its deviation, or false while
+	// the baseline has too few buckets
+	expect() (rate, deviation float64, ok bool)
+	// add adds the rate of the next bucket, which is clamped if it is
+	// anomalous
+	add(rate float64, anomalous bool)
+}
+
+// anomalyDetector flags the timeline buckets whose ERROR or WARN rate rose
+// more than threshold deviations above a baseline of the buckets before them.
+// Buckets with fewer than minLines lines are too noisy and are skipped.
+type anomalyDetector struct {
+	method     string
+	threshold  float64
+	window     int
+	minLines   int
+	bucketSize time.Duration
+}
+
+// newAnomalyDetector creates a detector using one of the methods, or nil if
+// detection is disabled. The ewma method compares buckets to an exponentially
+// weighted moving average and variance spanning window buckets, the mad
+// method to the median and median absolute deviation of the last window
+// buckets, which is at least minBaselineBuckets.
+func newAnomalyDetector(method string, threshold float64, window, minLines int, bucketSize time.Duration) (*anomalyDetector, error) {
+	switch method {
+	case AnomalyNone:
+		return nil, nil
+	case AnomalyEWMA, AnomalyMAD, "":
+	default:
+		return nil, fmt.Errorf("unknown anomaly detection method %q", method)
+	}
+	if method == "" {
+		method = AnomalyEWMA
+	}
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+	if window <= 0 {
+		window = defaultAnomalyWindow
+	}
+	window = max(window, minBaselineBuckets)
+	return &anomalyDetector{
+		method:     method,
+		threshold:  threshold,
+		window:     window,
+		minLines:   minLines,
+		bucketSize: bucketSize,
+	}, nil
+}
+
+func (d *anomalyDetector) newBaseline() rateBaseline {
+	if d.method == AnomalyMAD {
+		return &madBaseline{window: d.window}
+	}
+	return &ewmaBaseline{alpha: 2 / (float64(d.window) + 1)}
+}
+
+// Detect returns the anomalies of a timeline by level and start. Consecutive
+// anomalous buckets of a level form one anomaly.
+func (d *anomalyDetector) Detect(timeline *models.Timeline) []models.Anomaly {
+	if d == nil || timeline == nil {
+		return nil
+	}
+	var anomalies []models.Anomaly
+	for _, level := range anomalyLevels {
+		anomalies = append(anomalies, d.detectLevel(timeline.Buckets, level)...)
+	}
+	return anomalies
+}
+
+func (d *anomalyDetector) detectLevel(buckets []models.TimelineBucket, level string) []models.Anomaly {
+	baseline := d.newBaseline()
+	var anomalies []models.Anomaly
+	var current *models.Anomaly
+	var expectedCount float64
+	closeCurrent := func() {
+		if current == nil {
+			return
+		}
+		current.Observed = float64(current.Count) / float64(current.Lines)
+		current.Expected = expectedCount / float64(current.Lines)
+		current.Severity = d.severity(current.Score)
+		anomalies = append(anomalies, *current)
+		current = nil
+	}
+
+	for _, bucket := range buckets {
+		lines := 0
+		for _, count := range bucket.Counts {
+			lines += count
+		}
+		if lines < d.minLines {
+			closeCurrent()
+			continue
+		}
+		count := bucket.Counts[level]
+		rate := float64(count) / float64(lines)
+
+		expected, deviation, ok := baseline.expect()
+		if !ok {
+			baseline.add(rate, false)
+			continue
+		}
+		// The rate of a bucket varies by chance at least as much as a
+		// binomial draw of its lines
+		deviation = max(deviation, math.Sqrt(expected*(1-expected)/float64(lines)), minRateDeviation)
+		score := (rate - expected) / deviation
+		// Anomalous rates are clamped to the threshold before they join the
+		// baseline, so an incident is not absorbed by it while a lasting
+		// change still is, gradually
+		baseline.add(min(rate, expected+d.threshold*deviation), score >= d.threshold)
+		if score < d.threshold {
+			closeCurrent()
+			continue
+		}
+
+		if current != nil && !bucket.Start.Equal(current.End) {
+			closeCurrent()
+		}
+		if current == nil {
+			current = &models.Anomaly{Level: level, Start: bucket.Start}
+			expectedCount = 0
+		}
+		current.End = bucket.Start.Add(d.bucketSize)
+		current.Lines += lines
+		current.Count += count
+		current.Score = max(current.Score, score)
+		expectedCount += expected * float64(lines)
+	}
+	closeCurrent()
+	return anomalies
+}
+
+// severity grades an anomaly by its largest score
+func (d *anomalyDetector) severity(score float64) string {
+	switch {
+	case score >= 2*d.threshold:
+		return models.SeverityHigh
+	case score >= 1.5*d.threshold:
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
+
+// ewmaBaseline is an exponentially weighted moving average and variance of
+// the rates
+type ewmaBaseline struct {
+	alpha    float64
+	mean     float64
+	variance float64
+	buckets  int
+}
+
+func (b *ewmaBaseline) expect() (float64, float64, bool) {
+	return b.mean, math.Sqrt(b.variance), b.buckets >= minBaselineBuckets
+}
+
+// add moves the average towards the rate, anomalous rates leave the variance
+// as it is so the clamped rates do not widen it
+func (b *ewmaBaseline) add(rate float64, anomalous bool) {
+	b.buckets++
+	if b.buckets == 1 {
+		b.mean = rate
+		return
+	}
+	diff := rate - b.mean
+	increment := b.alpha * diff
+	b.mean += increment
+	if !anomalous {
+		b.variance = (1 - b.alpha) * (b.variance + diff*increment)
+	}
+}
+
+// madBaseline is the median and scaled median absolute deviation of the
+// rates of the last window buckets
+type madBaseline struct {
+	window int
+	rates  []float64
+}
+
+func (b *madBaseline) expect() (float64, float64, bool) {
+	if len(b.rates) < minBaselineBuckets {
+		return 0, 0, false
+	}
+	median := medianOf(slices.Clone(b.rates))
+	deviations := make([]float64, len(b.rates))
+	for i, rate := range b.rates {
+		deviations[i] = math.Abs(rate - median)
+	}
+	return median, madScale * medianOf(deviations), true
+}
+
+func (b *madBaseline) add(rate float64, _ bool) {
+	if len(b.rates) == b.window {
+		b.rates = append(b.rates[:0], b.rates[1:]...)
+	}
+	b.rates = append(b.rates, rate)
+}
+
+// medianOf returns the median of values, which it sorts
+func medianOf(values []float64) float64 {
+	slices.Sort(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
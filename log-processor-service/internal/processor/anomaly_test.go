@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// anomalyStart is the start of the synthetic timelines
+var anomalyStart = time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+
+// anomalyTimeline builds a timeline of one minute buckets of 1000 lines, 50 of
+// them WARN and errors[i] of them ERROR. A negative count leaves the bucket
+// out, a count above 1000 makes a bucket of 10 lines.
+func anomalyTimeline(errors []int) *models.Timeline {
+	timeline := &models.Timeline{BucketSize: "1m0s"}
+	for i, count := range errors {
+		start := anomalyStart.Add(time.Duration(i) * time.Minute)
+		switch {
+		case count < 0:
+			continue
+		case count > 1000:
+			timeline.Buckets = append(timeline.Buckets, models.TimelineBucket{Start: start, Counts: map[string]int{LevelInfo: 10}})
+			continue
+		}
+		counts := map[string]int{LevelWarn: 50, LevelError: count, LevelInfo: 950 - count}
+		timeline.Buckets = append(timeline.Buckets, models.TimelineBucket{Start: start, Counts: counts})
+	}
+	return timeline
+}
+
+// flatErrors returns n buckets at an error rate of 1%
+func flatErrors(n int, then ...int) []int {
+	errors := make([]int, n)
+	for i := range errors {
+		errors[i] = 10
+	}
+	return append(errors, then...)
+}
+
+func TestAnomalyDetector(t *testing.T) {
+	// A flat rate of 1% over 1000 lines deviates by chance by
+	// sqrt(0.01*0.99/1000) = 0.31%, so 25 errors score 4.8, 32 errors 7.0
+	// and 40 errors 9.5 deviations
+	tests := []struct {
+		name   string
+		errors []int
+		want   []models.Anomaly // Level, Start, End, Lines, Count and Severity
+	}{
+		{name: "flat", errors: flatErrors(40)},
+		{name: "within the binomial deviation", errors: flatErrors(20, 15, 10, 5, 10)},
+		{name: "too few buckets for a baseline", errors: []int{10, 10, 10, 10, 60}},
+		{
+			name:   "low",
+			errors: flatErrors(20, 25, 10),
+			want:   []models.Anomaly{{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(21 * time.Minute), Lines: 1000, Count: 25, Severity: models.SeverityLow}},
+		},
+		{
+			name:   "medium",
+			errors: flatErrors(20, 32, 10),
+			want:   []models.Anomaly{{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(21 * time.Minute), Lines: 1000, Count: 32, Severity: models.SeverityMedium}},
+		},
+		{
+			name:   "single spike",
+			errors: flatErrors(20, 40, 10, 10),
+			want:   []models.Anomaly{{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(21 * time.Minute), Lines: 1000, Count: 40, Severity: models.SeverityHigh}},
+		},
+		{
+			name:   "adjacent spikes merge",
+			errors: flatErrors(20, 40, 40, 10),
+			want:   []models.Anomaly{{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(22 * time.Minute), Lines: 2000, Count: 80, Severity: models.SeverityHigh}},
+		},
+		{
+			name:   "missing bucket splits spikes",
+			errors: flatErrors(20, 40, -1, 40, 10),
+			want: []models.Anomaly{
+				{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(21 * time.Minute), Lines: 1000, Count: 40, Severity: models.SeverityHigh},
+				{Start: anomalyStart.Add(22 * time.Minute), End: anomalyStart.Add(23 * time.Minute), Lines: 1000, Count: 40, Severity: models.SeverityHigh},
+			},
+		},
+		{
+			name:   "sparse bucket splits spikes",
+			errors: flatErrors(20, 40, 1001, 40, 10),
+			want: []models.Anomaly{
+				{Start: anomalyStart.Add(20 * time.Minute), End: anomalyStart.Add(21 * time.Minute), Lines: 1000, Count: 40, Severity: models.SeverityHigh},
+				{Start: anomalyStart.Add(22 * time.Minute), End: anomalyStart.Add(23 * time.Minute), Lines: 1000, Count: 40, Severity: models.SeverityHigh},
+			},
+		},
+	}
+	for _, method := range []string{AnomalyEWMA, AnomalyMAD} {
+		detector, err := newAnomalyDetector(method, 4, 30, 20, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			t.Run(method+"/"+tt.name, func(t *testing.T) {
+				got := detector.Detect(anomalyTimeline(tt.errors))
+				if len(got) != len(tt.want) {
+					t.Fatalf("got %d anomalies %+v, want %d", len(got), got, len(tt.want))
+				}
+				for i, want := range tt.want {
+					a := got[i]
+					if a.Level != LevelError || !a.Start.Equal(want.Start) || !a.End.Equal(want.End) ||
+						a.Lines != want.Lines || a.Count != want.Count || a.Severity != want.Severity {
+						t.Errorf("anomaly %d = %+v, want %+v", i, a, want)
+					}
+					if observed := float64(want.Count) / float64(want.Lines); math.Abs(a.Observed-observed) > 1e-9 || math.Abs(a.Expected-0.01) > 0.001 {
+						t.Errorf("anomaly %d observed %v and expected %v, want %v and about 0.01", i, a.Observed, a.Expected, observed)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestAnomalyBaselineFollowsLastingChange checks that a rate that stays up is
+// absorbed by the baseline rather than flagged for good
+func TestAnomalyBaselineFollowsLastingChange(t *testing.T) {
+	errors := flatErrors(20)
+	for range 200 {
+		errors = append(errors, 40)
+	}
+	for _, method := range []string{AnomalyEWMA, AnomalyMAD} {
+		detector, err := newAnomalyDetector(method, 4, 30, 20, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		anomalies := detector.Detect(anomalyTimeline(errors))
+		if len(anomalies) != 1 || !anomalies[0].Start.Equal(anomalyStart.Add(20*time.Minute)) || !anomalies[0].End.Before(anomalyStart.Add(220*time.Minute)) {
+			t.Errorf("%s: got anomalies %+v, want one ending before the timeline", method, anomalies)
+		}
+	}
+}
+
+func TestNewAnomalyDetector(t *testing.T) {
+	if detector, err := newAnomalyDetector(AnomalyNone, 0, 0, 0, time.Minute); detector != nil || err != nil {
+		t.Errorf("got %v, %v for method none, want no detector", detector, err)
+	}
+	if _, err := newAnomalyDetector("zscore", 0, 0, 0, time.Minute); err == nil {
+		t.Error("created a detector with an unknown method")
+	}
+	detector, err := newAnomalyDetector("", 0, 2, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detector.method != AnomalyEWMA || detector.threshold != defaultAnomalyThreshold || detector.window != minBaselineBuckets {
+		t.Errorf("got method %q, threshold %v and window %d", detector.method, detector.threshold, detector.window)
+	}
+}
@@ -0,0 +1,332 @@
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// Archive formats whose members are processed as files of their own
+const (
+	ArchiveZip = "zip"
+	ArchiveTar = "tar"
+)
+
+var (
+	errTooManyMembers     = errors.New("archive has too many members")
+	errArchiveTooLarge    = errors.New("archive expands beyond the size limit")
+	errCompressionTooHigh = errors.New("member exceeds the compression ratio limit")
+)
+
+var (
+	zipMagic = []byte("PK\x03\x04")
+	tarMagic = []byte("ustar")
+)
+
+// tarMagicOffset is where the magic of a POSIX tar header starts
+const tarMagicOffset = 257
+
+// detectArchive returns the archive format of a file, looking into the
+// decompressed stream for tarballs, or an empty string for plain log files
+func detectArchive(file *os.File, size int64, compression string) (string, error) {
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+
+	var source io.Reader = io.NewSectionReader(file, 0, size)
+	if compression != "" {
+		decompressor, err := newDecompressor(source, compression)
+		if err != nil {
+			return "", err
+		}
+		defer decompressor.Close()
+		source = decompressor
+	}
+
+	n, err := io.ReadFull(source, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	switch {
+	case compression == "" && bytes.HasPrefix(header[:n], zipMagic):
+		return ArchiveZip, nil
+	case n == len(header) && bytes.Equal(header[tarMagicOffset:], tarMagic):
+		return ArchiveTar, nil
+	default:
+		return "", nil
+	}
+}
+
+// memberName cleans the path of an archive member, rejecting absolute paths
+// and paths that escape the archive (zip-slip)
+func memberName(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") ||
+		(len(cleaned) > 1 && cleaned[1] == ':') {
+		return "", fmt.Errorf("unsafe member path %q", name)
+	}
+	return cleaned, nil
+}
+
+// skipMember reports whether a member is archiver metadata rather than a log
+func skipMember(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || path.Base(name) == ".DS_Store"
+}
+
+// archiveScan processes the members of one archive, enforcing the member
+// count limit and the budget of bytes extracted over all members
+type archiveScan struct {
+	p         *Processor
+	job       *fileJob
+	remaining int64
+	members   int
+	results   []models.Result
+}
+
+// accept validates a member before it is read and returns its cleaned name,
+// or false if the member is skipped
+func (a *archiveScan) accept(name string, regular bool) (string, bool, error) {
+	if !regular {
+		return "", false, nil
+	}
+	name, err := memberName(name)
+	if err != nil {
+		return "", false, err
+	}
+	if skipMember(name) {
+		return "", false, nil
+	}
+	if a.members++; a.members > a.p.maxArchiveMembers {
+		return "", false, errTooManyMembers
+	}
+	return name, true, nil
+}
+
+// scan processes one member as a file of its own. Members that cannot be read
+// get a failed result, but exceeding a limit or a cancelled ctx aborts the
+// whole archive.
+func (a *archiveScan) scan(ctx context.Context, name string, size int64, member io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	displayName := filepath.Base(a.job.filePath) + "/" + name
+	progress := newFileProgress(displayName, size, a.job.progressCb)
+
+	state, err := a.scanMember(ctx, name, member, progress)
+	if errors.Is(err, errArchiveTooLarge) {
+		return err
+	}
+
+	var result models.Result
+	switch {
+	case err == nil:
+		result = state.finish(a.job.filePath+"/"+name, a.p.topN, a.p.topK)
+		a.job.progressCb(displayName, 100, "completed", nil)
+	case cancelled(err):
+		result = state.finish(a.job.filePath+"/"+name, a.p.topN, a.p.topK)
+		result.MarkPartial()
+		a.job.progressCb(displayName, progress.reported(), "cancelled", err)
+	default:
+		result = models.NewFailedResult(a.job.filePath+"/"+name, err, Categorize(err))
+		a.job.progressCb(displayName, 0, "error", err)
+		fmt.Printf("Error processing member %s: %v\n", displayName, err)
+		err = nil
+	}
+	result.Archive = filepath.Base(a.job.filePath)
+	result.Member = name
+
+	a.results = append(a.results, result)
+	fmt.Println(result.String())
+	return err
+}
+
+// scanMember scans the lines of a member, decompressing rotated logs such as
+// app.log.gz inside the archive. Only the bytes scanned count against the
+// budget of the archive, so a compressed member counts once, decompressed.
+func (a *archiveScan) scanMember(ctx context.Context, name string, member io.Reader, progress *fileProgress) (*scanState, error) {
+	buffered := bufio.NewReader(&countingReader{r: member, progress: progress})
+	header, _ := buffered.Peek(4)
+	compression := compressionOf(header)
+
+	var source io.Reader = buffered
+	if compression != "" {
+		decompressor, err := newDecompressor(buffered, compression)
+		if err != nil {
+			return nil, err
+		}
+		defer decompressor.Close()
+		source = decompressor
+	}
+	source = &budgetReader{r: source, scan: a}
+
+	format := a.job.format
+	if format == "" {
+		format = formatOf(name)
+	}
+	state, err := a.p.scanLines(ctx, source, a.p.newFormatState(format, a.job.columns, a.job.filter), nil, nil)
+	if state != nil {
+		state.result.Compression = compression
+	}
+	return state, err
+}
+
+// budgetReader fails with errArchiveTooLarge once the members of an archive
+// expanded to more bytes than allowed
+type budgetReader struct {
+	r    io.Reader
+	scan *archiveScan
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	if br.scan.remaining < 0 {
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > br.scan.remaining+1 {
+		p = p[:br.scan.remaining+1]
+	}
+	n, err := br.r.Read(p)
+	br.scan.remaining -= int64(n)
+	if br.scan.remaining < 0 {
+		return n, errArchiveTooLarge
+	}
+	return n, err
+}
+
+// scanArchive processes every member of an archive and returns their results
+// followed by the total of the archive. When ctx is cancelled the members
+// scanned so far are returned with a partial total along with ctx.Err().
+func (p *Processor) scanArchive(ctx context.Context, job *fileJob) ([]models.Result, error) {
+	file, err := os.Open(job.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", job.filePath, err)
+	}
+	defer file.Close()
+
+	// Expanding to more than the compression ratio allows is treated as a bomb
+	a := &archiveScan{p: p, job: job, remaining: p.maxArchiveBytes}
+	if p.maxCompressionRatio > 0 {
+		a.remaining = min(a.remaining, job.size*p.maxCompressionRatio)
+	}
+
+	switch job.archive {
+	case ArchiveZip:
+		err = p.scanZip(ctx, file, a)
+	case ArchiveTar:
+		err = p.scanTar(ctx, file, a)
+	default:
+		err = fmt.Errorf("unsupported archive %q", job.archive)
+	}
+	if err != nil && !cancelled(err) {
+		return nil, fmt.Errorf("error reading archive %s: %w", job.filePath, err)
+	}
+
+	total := models.Result{
+		FilePath:    job.filePath,
+		Members:     len(a.results),
+		Compression: job.compression,
+		Status:      models.StatusCompleted,
+		Classifier:  p.classifier.Name(),
+		Rules:       p.classifier.Rules(),
+	}
+	for _, result := range a.results {
+		total.Add(result)
+	}
+	if err != nil {
+		total.MarkPartial()
+	}
+	return append(a.results, total), err
+}
+
+// scanZip processes the members of a zip archive. Progress of the archive
+// follows the compressed size of the members read.
+func (p *Processor) scanZip(ctx context.Context, file *os.File, a *archiveScan) error {
+	source := &sourceReader{ra: file}
+	reader, err := zip.NewReader(source, a.job.size)
+	if err != nil {
+		return (&decodeReader{source: source}).mark(err)
+	}
+
+	for _, f := range reader.File {
+		name, ok, err := a.accept(f.Name, f.Mode().IsRegular())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		// Reject bombs from the declared sizes before inflating anything
+		if f.UncompressedSize64 > uint64(max(a.remaining, 0)) {
+			return errArchiveTooLarge
+		}
+		if p.maxCompressionRatio > 0 && f.UncompressedSize64 > f.CompressedSize64*uint64(p.maxCompressionRatio) {
+			return fmt.Errorf("%w: %s", errCompressionTooHigh, name)
+		}
+
+		member, err := f.Open()
+		dr := &decodeReader{r: member, source: source}
+		if err != nil {
+			return dr.mark(err)
+		}
+		err = a.scan(ctx, name, int64(f.UncompressedSize64), dr)
+		member.Close()
+		if err != nil {
+			return err
+		}
+		a.job.progress.advance(int64(f.CompressedSize64))
+	}
+	return nil
+}
+
+// scanTar processes the members of a tarball, which may be compressed as a
+// whole. Progress of the archive follows the bytes read from the file. The
+// members skipped count against the budget too, as they are expanded to be
+// read past.
+func (p *Processor) scanTar(ctx context.Context, file *os.File, a *archiveScan) error {
+	var source io.Reader = &countingReader{r: io.NewSectionReader(file, 0, a.job.size), progress: a.job.progress}
+	if a.job.compression != "" {
+		decompressor, err := newDecompressor(source, a.job.compression)
+		if err != nil {
+			return err
+		}
+		defer decompressor.Close()
+		source = decompressor
+	}
+	tarSource := &sourceReader{r: source}
+
+	reader := tar.NewReader(tarSource)
+	member := &decodeReader{r: reader, source: tarSource}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return member.mark(err)
+		}
+
+		name, ok, err := a.accept(header.Name, header.Typeflag == tar.TypeReg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if a.remaining -= header.Size; a.remaining < 0 {
+				return errArchiveTooLarge
+			}
+			continue
+		}
+		if err := a.scan(ctx, name, header.Size, member); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+)
+
+// archiveMember is a file written into a test archive
+type archiveMember struct {
+	name   string
+	data   []byte
+	method uint16 // zip compression method
+}
+
+func writeZip(t *testing.T, path string, members []archiveMember) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, member := range members {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: member.name, Method: member.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, members []archiveMember) {
+	t.Helper()
+	var buf bytes.Buffer
+	compressed := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(compressed)
+	for _, member := range members {
+		header := &tar.Header{Name: member.name, Mode: 0644, Size: int64(len(member.data)), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMemberName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{name: "app.log", want: "app.log", ok: true},
+		{name: "./logs/app.log", want: "logs/app.log", ok: true},
+		{name: "logs/../app.log", want: "app.log", ok: true},
+		{name: `logs\app.log`, want: "logs/app.log", ok: true},
+		{name: "../app.log"},
+		{name: "logs/../../app.log"},
+		{name: `..\..\app.log`},
+		{name: "/etc/passwd"},
+		{name: `C:\Windows\app.log`},
+		{name: ".."},
+	}
+	for _, tt := range tests {
+		got, err := memberName(tt.name)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("memberName(%q) = %q, %v, want %q, ok %v", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestArchiveLimits(t *testing.T) {
+	plain := generatorLog(64 * 1024)
+	repeated := []byte(strings.Repeat("[2025-04-01T10:00:00Z] INFO same line again\n", 4096))
+	small := []byte("[2025-04-01T10:00:00Z] INFO started\n")
+
+	tests := []struct {
+		name    string
+		archive string
+		members []archiveMember
+		limits  func(cfg *config.Config)
+		err     error
+		results int // member results, without the total of the archive
+	}{
+		{
+			name:    "zip slip",
+			archive: "logs.zip",
+			members: []archiveMember{{name: "app.log", data: small}, {name: "../../etc/cron.d/app.log", data: small}},
+			err:     errors.New("unsafe member path"),
+		},
+		{
+			name:    "members within the limit",
+			archive: "logs.zip",
+			members: []archiveMember{
+				{name: "a.log", data: small},
+				{name: "__MACOSX/._a.log", data: small},
+				{name: "b.log", data: small},
+			},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveMembers = 2 },
+			results: 2,
+		},
+		{
+			name:    "too many members",
+			archive: "logs.zip",
+			members: []archiveMember{{name: "a.log", data: small}, {name: "b.log", data: small}, {name: "c.log", data: small}},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveMembers = 2 },
+			err:     errTooManyMembers,
+		},
+		{
+			// The stored member makes the archive large enough for the
+			// expanded size of both
+			name:    "zip compression ratio",
+			archive: "logs.zip",
+			members: []archiveMember{{name: "plain.log", data: plain}, {name: "app.log", data: repeated, method: zip.Deflate}},
+			limits:  func(cfg *config.Config) { cfg.MaxCompressionRatio = 10 },
+			err:     errCompressionTooHigh,
+		},
+		{
+			name:    "tarball compression ratio",
+			archive: "logs.tar.gz",
+			members: []archiveMember{{name: "app.log", data: repeated}},
+			limits:  func(cfg *config.Config) { cfg.MaxCompressionRatio = 10 },
+			err:     errArchiveTooLarge,
+		},
+		{
+			// The member counts once decompressed, not also compressed
+			name:    "compressed member within the budget",
+			archive: "logs.zip",
+			members: []archiveMember{{name: "app.log.gz", data: gzipBytes(t, plain)}},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) },
+			results: 1,
+		},
+		{
+			name:    "compressed member beyond the budget",
+			archive: "logs.zip",
+			members: []archiveMember{{name: "app.log.gz", data: gzipBytes(t, plain)}},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) - 1 },
+			err:     errArchiveTooLarge,
+		},
+		{
+			name:    "members beyond the budget",
+			archive: "logs.tar.gz",
+			members: []archiveMember{{name: "a.log", data: plain}, {name: "b.log", data: plain}},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) * 3 / 2 },
+			err:     errArchiveTooLarge,
+		},
+		{
+			name:    "skipped members beyond the budget",
+			archive: "logs.tar.gz",
+			members: []archiveMember{{name: "__MACOSX/._a.log", data: plain}, {name: "a.log", data: small}},
+			limits:  func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) - 1 },
+			err:     errArchiveTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if strings.HasSuffix(tt.archive, ".zip") {
+				writeZip(t, filepath.Join(dir, tt.archive), tt.members)
+			} else {
+				writeTarGz(t, filepath.Join(dir, tt.archive), tt.members)
+			}
+			cfg := config.NewConfig()
+			cfg.UploadDir = dir
+			cfg.MaxCompressionRatio = 0
+			if tt.limits != nil {
+				tt.limits(cfg)
+			}
+			p, err := NewProcessor(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			outcomes, err := p.ProcessFiles(context.Background(), []string{tt.archive}, JobOptions{}, nil, func(string, int, string, error) {}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outcome := outcomes[0]
+			switch {
+			case tt.err == nil && outcome.Err != nil:
+				t.Fatalf("got error %v", outcome.Err)
+			case tt.err != nil && !errors.Is(outcome.Err, tt.err) && (outcome.Err == nil || !strings.Contains(outcome.Err.Error(), tt.err.Error())):
+				t.Fatalf("got error %v, want %v", outcome.Err, tt.err)
+			case tt.err == nil && len(outcome.Results) != tt.results+1:
+				t.Fatalf("got %d results, want %d members and the total", len(outcome.Results), tt.results)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// Checkpoint is the saved progress of a chunk of a file, from which processing
+// resumes after a restart. Files larger than the chunk threshold have one
+// checkpoint per chunk, other files a single one. Archives are not checkpointed
+// and start over.
+type Checkpoint struct {
+	FileName string `json:"file_name"`
+	Size     int64  `json:"size"`
+	Chunk    int    `json:"chunk"`
+	Chunks   int    `json:"chunks"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+
+	// Offset is the number of bytes of the chunk scanned, counting decompressed
+	// bytes for compressed files
+	Offset int64 `json:"offset"`
+	Done   bool  `json:"done"`
+
+	// State is the analysis of the bytes scanned
+	State *stateSnapshot `json:"state,omitempty"`
+}
+
+// CheckpointCallback persists a checkpoint, replacing the earlier one of the
+// same chunk. It is called from the workers and must not keep the checkpoint
+// beyond the call.
+type CheckpointCallback func(checkpoint Checkpoint)
+
+// stateSnapshot is the serialisable form of a scanState
+type stateSnapshot struct {
+	Result      models.Result            `json:"result"`
+	Lines       int                      `json:"lines"`
+	ParseErrors []parseErrorSnapshot     `json:"parse_errors,omitempty"`
+	Timeline    map[int64]map[string]int `json:"timeline,omitempty"`
+	Templates   []clusterSnapshot        `json:"templates,omitempty"`
+	Fields      map[string]fieldSnapshot `json:"fields,omitempty"`
+	Exceptions  []models.ExceptionStats  `json:"exceptions,omitempty"`
+	Samples     []models.Sample          `json:"samples,omitempty"`
+
+	AtStart bool           `json:"at_start"`
+	Head    *entrySnapshot `json:"head,omitempty"`
+	Entry   *entrySnapshot `json:"entry,omitempty"`
+
+	Format string       `json:"format,omitempty"`
+	CSV    *csvSnapshot `json:"csv,omitempty"`
+}
+
+// csvSnapshot is the layout of a CSV file, whose header is only read once
+type csvSnapshot struct {
+	Mapping models.ColumnMapping `json:"mapping"`
+	Header  []string             `json:"header,omitempty"`
+}
+
+type parseErrorSnapshot struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// entrySnapshot is the serialisable form of a pendingEntry
+type entrySnapshot struct {
+	Start string   `json:"start,omitempty"`
+	Level string   `json:"level,omitempty"`
+	Lines []string `json:"lines,omitempty"`
+	Last  string   `json:"last,omitempty"`
+	Count int      `json:"count"`
+
+	// Unparsed and First are the continuation lines that are not blank and
+	// the first of them
+	Unparsed int `json:"unparsed,omitempty"`
+	First    int `json:"first,omitempty"`
+
+	// Excluded is the filter that excluded the entry, if any
+	Excluded string `json:"excluded,omitempty"`
+}
+
+func (e *pendingEntry) snapshot() *entrySnapshot {
+	if e == nil {
+		return nil
+	}
+	return &entrySnapshot{
+		Start:    string(e.start),
+		Level:    e.level,
+		Lines:    append([]string(nil), e.lines...),
+		Last:     e.last,
+		Count:    e.count,
+		Unparsed: e.unparsed,
+		First:    e.first,
+		Excluded: e.excluded,
+	}
+}
+
+func (e *entrySnapshot) restore() *pendingEntry {
+	return &pendingEntry{
+		start:    []byte(e.Start),
+		level:    e.Level,
+		lines:    e.Lines,
+		last:     e.Last,
+		count:    e.Count,
+		unparsed: e.Unparsed,
+		first:    e.First,
+		excluded: e.Excluded,
+	}
+}
+
+func (s *scanState) snapshot() *stateSnapshot {
+	snapshot := &stateSnapshot{
+		Result:    s.result,
+		Lines:     s.lines,
+		Timeline:  s.timeline.snapshot(),
+		Templates: s.templates.snapshot(),
+		Fields:    s.fields.snapshot(),
+		Samples:   s.samples.snapshot(),
+		AtStart:   s.atStart,
+		Entry:     s.entry.snapshot(),
+		Format:    s.format,
+	}
+	if s.head.count > 0 {
+		snapshot.Head = s.head.snapshot()
+	}
+	if s.csv != nil {
+		snapshot.CSV = &csvSnapshot{Mapping: s.csv.mapping, Header: s.csv.header}
+	}
+	for _, exception := range s.exceptions.counts {
+		snapshot.Exceptions = append(snapshot.Exceptions, *exception)
+	}
+	for _, parseErr := range s.parseErrors {
+		snapshot.ParseErrors = append(snapshot.ParseErrors, parseErrorSnapshot{Line: parseErr.line, Error: parseErr.err.Error()})
+	}
+	return snapshot
+}
+
+// restoreScanState rebuilds the state saved in a snapshot of a file whose
+// entries pass the filter
+func (p *Processor) restoreScanState(snapshot *stateSnapshot, filter *entryFilter) *scanState {
+	var mapping models.ColumnMapping
+	if snapshot.CSV != nil {
+		mapping = snapshot.CSV.Mapping
+	}
+	state := p.newFormatState(snapshot.Format, mapping, filter)
+	state.result = snapshot.Result
+	state.lines = snapshot.Lines
+	for _, parseErr := range snapshot.ParseErrors {
+		state.parseErrors = append(state.parseErrors, parseError{line: parseErr.Line, err: errors.New(parseErr.Error)})
+	}
+	state.timeline.restore(snapshot.Timeline)
+	state.templates.restore(snapshot.Templates)
+	state.fields.restore(snapshot.Fields)
+	state.samples.restore(snapshot.Samples)
+	for _, exception := range snapshot.Exceptions {
+		copied := exception
+		state.exceptions.counts[exceptionKey{typ: exception.Type, frame: exception.TopFrame}] = &copied
+	}
+
+	state.atStart = snapshot.AtStart
+	if snapshot.Head != nil {
+		state.head = *snapshot.Head.restore()
+	}
+	if snapshot.Entry != nil {
+		state.entry = snapshot.Entry.restore()
+	}
+
+	if snapshot.CSV != nil && snapshot.CSV.Header != nil {
+		header := make([][]byte, len(snapshot.CSV.Header))
+		for i, column := range snapshot.CSV.Header {
+			header[i] = []byte(column)
+		}
+		// The header was accepted when it was read
+		state.csv.setHeader(header)
+	}
+	return state
+}
+
+// newCheckpoint saves the state of a chunk, which is nil before it starts
+func newCheckpoint(task chunkTask, state *scanState, done bool) Checkpoint {
+	checkpoint := Checkpoint{
+		FileName: filepath.Base(task.job.filePath),
+		Size:     task.job.size,
+		Chunk:    task.index,
+		Chunks:   len(task.job.states),
+		Start:    task.r.start,
+		End:      task.r.end,
+		Done:     done,
+	}
+	if state != nil {
+		checkpoint.Offset = state.result.TotalBytes
+		checkpoint.State = state.snapshot()
+	}
+	return checkpoint
+}
+
+// resumeTasks recreates the chunks of a file from the checkpoints of an earlier
+// run, or returns nil if there are none or they do not match the file
+func (p *Processor) resumeTasks(job *fileJob, saved []Checkpoint) []chunkTask {
+	if len(saved) == 0 || job.archive != "" {
+		return nil
+	}
+
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Chunk < saved[j].Chunk })
+	for i, checkpoint := range saved {
+		if checkpoint.Chunk != i || checkpoint.Chunks != len(saved) || checkpoint.Size != job.size {
+			fmt.Printf("Ignoring checkpoints of %s, the file changed since\n", job.filePath)
+			return nil
+		}
+	}
+
+	tasks := make([]chunkTask, len(saved))
+	for i, checkpoint := range saved {
+		tasks[i] = chunkTask{
+			job:   job,
+			index: i,
+			r:     byteRange{start: checkpoint.Start, end: checkpoint.End},
+			done:  checkpoint.Done,
+		}
+		if checkpoint.State != nil {
+			tasks[i].resume = p.restoreScanState(checkpoint.State, job.filter)
+		}
+	}
+	fmt.Printf("Resuming file %s from %d checkpoints\n", job.filePath, len(tasks))
+	return tasks
+}
+
+// checkpointer saves the progress of a chunk every checkpoint interval
+type checkpointer struct {
+	task     chunkTask
+	save     CheckpointCallback
+	interval time.Duration
+	last     time.Time
+}
+
+// newCheckpointer returns nil when the chunk is not checkpointed
+func (p *Processor) newCheckpointer(task chunkTask) *checkpointer {
+	if task.job.checkpointCb == nil || task.job.archive != "" {
+		return nil
+	}
+	return &checkpointer{
+		task:     task,
+		save:     task.job.checkpointCb,
+		interval: p.checkpointInterval,
+		last:     time.Now(),
+	}
+}
+
+// step saves the state once the checkpoint interval has passed. It is called
+// between lines, so the state covers exactly the bytes scanned.
+func (c *checkpointer) step(state *scanState) {
+	if c == nil || time.Since(c.last) < c.interval {
+		return
+	}
+	c.flush(state, false)
+}
+
+// flush saves the state right away
+func (c *checkpointer) flush(state *scanState, done bool) {
+	if c == nil {
+		return
+	}
+	c.last = time.Now()
+	c.save(newCheckpoint(c.task, state, done))
+}
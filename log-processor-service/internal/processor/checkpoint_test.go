@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// TestResumeMatchesUninterruptedScan cancels the scan of a chunked file once
+// some of its chunks are part-way through, resumes it from the checkpoints
+// saved and compares the result and index with those of a scan that was not
+// interrupted
+func TestResumeMatchesUninterruptedScan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), generatorLog(8*1024*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewConfig()
+	cfg.UploadDir = dir
+	cfg.ChunkThreshold = 2 * 1024 * 1024
+	cfg.ChunkSize = 2 * 1024 * 1024
+	cfg.CheckpointInterval = time.Nanosecond
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{"app.log"}
+	opts := JobOptions{Index: true}
+	noProgress := func(string, int, string, error) {}
+	indexFile := indexPath(dir, "app.log")
+
+	want, err := p.ProcessFiles(context.Background(), files, opts, nil, noProgress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndex, err := os.ReadFile(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(indexFile)
+
+	// Checkpoints go through JSON as they do through Redis
+	var mu sync.Mutex
+	saved := make(map[int][]byte)
+	saves := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	save := func(checkpoint Checkpoint) {
+		data, err := json.Marshal(checkpoint)
+		if err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		saved[checkpoint.Chunk] = data
+		if saves++; saves == 12 {
+			cancel()
+		}
+	}
+	if _, err := p.ProcessFiles(ctx, files, opts, nil, noProgress, save); err != context.Canceled {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+
+	var checkpoints []Checkpoint
+	partWay := 0
+	for _, data := range saved {
+		var checkpoint Checkpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			t.Fatal(err)
+		}
+		if checkpoint.Offset > 0 && !checkpoint.Done {
+			partWay++
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	if len(checkpoints) != 4 || partWay == 0 {
+		t.Fatalf("got %d checkpoints, %d part-way through, want 4 with some part-way through", len(checkpoints), partWay)
+	}
+
+	got, err := p.ProcessFiles(context.Background(), files, opts, checkpoints, noProgress, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resultsJSON(t, got[0].Results), resultsJSON(t, want[0].Results)) {
+		t.Errorf("resumed result differs:\ngot  %s\nwant %s", resultsJSON(t, got[0].Results), resultsJSON(t, want[0].Results))
+	}
+	if got[0].RestoredLines == 0 {
+		t.Error("no lines restored from checkpoints")
+	}
+	gotIndex, err := os.ReadFile(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotIndex, wantIndex) {
+		t.Error("index of the resumed file differs")
+	}
+}
+
+// resultsJSON returns the results as compared by the tests, which is their
+// JSON form
+func resultsJSON(t *testing.T, results []models.Result) string {
+	t.Helper()
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// progressStep is the number of bytes scanned between progress updates
+const progressStep = 256 * 1024
+
+// byteRange is a newline aligned part of a file, end exclusive
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// splitFile divides a file into ranges of roughly chunkSize bytes, each ending
+// right after a newline so no line is split between two ranges
+func splitFile(r io.ReaderAt, size, chunkSize int64) ([]byteRange, error) {
+	if chunkSize <= 0 || size <= chunkSize {
+		return []byteRange{{start: 0, end: size}}, nil
+	}
+
+	var ranges []byteRange
+	start := int64(0)
+	for start < size {
+		end := start + chunkSize
+		if end >= size {
+			ranges = append(ranges, byteRange{start: start, end: size})
+			break
+		}
+
+		end, err := nextLineStart(r, end-1, size)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, byteRange{start: start, end: end})
+		start = end
+	}
+	return ranges, nil
+}
+
+// nextLineStart returns the offset just after the first newline at or after offset
+func nextLineStart(r io.ReaderAt, offset, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for offset < size {
+		n, err := r.ReadAt(buf, offset)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i) + 1, nil
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// fileProgress reports the progress of a file whose chunks may be scanned
+// by several workers at once
+type fileProgress struct {
+	fileName string
+	size     int64
+	read     atomic.Int64
+	cb       ProgressCallback
+
+	mu   sync.Mutex
+	last int
+}
+
+func newFileProgress(fileName string, size int64, cb ProgressCallback) *fileProgress {
+	return &fileProgress{fileName: fileName, size: size, cb: cb}
+}
+
+// advance records n scanned bytes and reports every 10%
+func (fp *fileProgress) advance(n int64) {
+	if fp.size <= 0 {
+		return
+	}
+	read := fp.read.Add(n)
+	progress := int((float64(read) / float64(fp.size)) * 100)
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	// Only report progress if it has changed by at least 10%
+	if progress >= fp.last+10 && progress < 100 {
+		fp.last = progress
+		fp.cb(fp.fileName, progress, "processing", nil)
+	}
+}
+
+// reported returns the last progress reported
+func (fp *fileProgress) reported() int {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return fp.last
+}
+
+// lastLineEnd returns the offset just after the last newline between offset
+// and size, or offset if there is none
+func lastLineEnd(r io.ReaderAt, offset, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for end := size; end > offset; {
+		start := max(offset, end-int64(len(buf)))
+		n, err := r.ReadAt(buf[:end-start], start)
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		end = start
+	}
+	return offset, nil
+}
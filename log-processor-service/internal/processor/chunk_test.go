@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestSplitFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		chunkSize int64
+		want      []byteRange
+	}{
+		{name: "single chunk", input: "a\nb\n", chunkSize: 10, want: []byteRange{{0, 4}}},
+		{name: "newline aligned", input: "aaa\nbbb\nccc\n", chunkSize: 4, want: []byteRange{{0, 4}, {4, 8}, {8, 12}}},
+		{name: "mid line", input: "aaa\nbbb\nccc\n", chunkSize: 5, want: []byteRange{{0, 8}, {8, 12}}},
+		{name: "line longer than a chunk", input: "a\n" + strings.Repeat("b", 20) + "\nc\n", chunkSize: 4, want: []byteRange{{0, 23}, {23, 25}}},
+		{name: "no trailing newline", input: "aaa\nbbb\nccc", chunkSize: 6, want: []byteRange{{0, 8}, {8, 11}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitFile(strings.NewReader(tt.input), int64(len(tt.input)), tt.chunkSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got ranges %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got ranges %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// mixedLog interleaves generator lines with stack traces, blank lines and
+// lines that are not part of any entry
+func mixedLog(size int) []byte {
+	var buf bytes.Buffer
+	for i, line := range bytes.SplitAfter(generatorLog(size), []byte("\n")) {
+		buf.Write(line)
+		switch {
+		case i%37 == 5:
+			buf.WriteString("java.lang.IllegalStateException: boom\n\tat com.example.App.run(App.java:10)\n\tat com.example.Main.main(Main.java:3)\n")
+		case i%89 == 7:
+			buf.WriteString("panic: runtime error: index out of range [5] with length 3\n\ngoroutine 1 [running]:\nmain.main()\n\t/app/main.go:5 +0x1d\n")
+		case i%53 == 11:
+			buf.WriteString("\n")
+		case i%101 == 13:
+			buf.WriteString("some junk\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// jsonLinesLog rewrites generator lines as JSON lines
+func jsonLinesLog(size int) []byte {
+	var buf bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimSpace(generatorLog(size)), []byte("\n")) {
+		entry, err := ParseLogEntry(line)
+		if err != nil {
+			panic(err)
+		}
+		data, _ := json.Marshal(map[string]any{
+			"timestamp": entry.Timestamp,
+			"level":     entry.Level,
+			"message":   entry.Message,
+			"userId":    entry.Payload["userId"],
+		})
+		buf.Write(append(data, '\n'))
+	}
+	return buf.Bytes()
+}
+
+// TestChunkedScanMatchesSingleChunk scans files split into many small chunks,
+// whose ends fall inside stack traces and entries, and compares the merged
+// result with a scan of the file in one chunk
+func TestChunkedScanMatchesSingleChunk(t *testing.T) {
+	files := map[string][]byte{
+		"app.log":   mixedLog(256 * 1024),
+		"app.jsonl": jsonLinesLog(128 * 1024),
+	}
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scan := func(chunkSize int64) []FileOutcome {
+		cfg := config.NewConfig()
+		cfg.UploadDir = dir
+		cfg.ChunkThreshold = 1
+		cfg.ChunkSize = chunkSize
+		p, err := NewProcessor(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outcomes, err := p.ProcessFiles(context.Background(), []string{"app.log", "app.jsonl"}, JobOptions{}, nil, func(string, int, string, error) {}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return outcomes
+	}
+
+	want := scan(1 << 30)
+	got := scan(1024)
+	for i, outcome := range got {
+		if outcome.Err != nil || want[i].Err != nil {
+			t.Fatalf("%s: got errors %v and %v", outcome.FileName, outcome.Err, want[i].Err)
+		}
+		if outcome.Results[0].ContinuationLines == 0 && outcome.FileName == "app.log" {
+			t.Errorf("%s: no continuation lines scanned", outcome.FileName)
+		}
+		if resultsJSON(t, outcome.Results) != resultsJSON(t, want[i].Results) {
+			t.Errorf("%s: chunked result differs:\ngot  %s\nwant %s", outcome.FileName, resultsJSON(t, outcome.Results), resultsJSON(t, want[i].Results))
+		}
+	}
+}
+
+// TestCancelledJobIsPartial cancels a job part-way through its chunks, which a
+// single worker scans in order, and checks the partial result counts exactly
+// the lines scanned before the cancellation
+func TestCancelledJobIsPartial(t *testing.T) {
+	data := mixedLog(2 * 1024 * 1024)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewConfig()
+	cfg.UploadDir = dir
+	cfg.ChunkThreshold = 1
+	cfg.ChunkSize = 512 * 1024
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.numWorkers = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := func(_ string, percent int, status string, _ error) {
+		if status == "processing" && percent >= 40 {
+			cancel()
+		}
+	}
+	outcomes, err := p.ProcessFiles(ctx, []string{"app.log"}, JobOptions{}, nil, progress, nil)
+	if err != context.Canceled {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	outcome := outcomes[0]
+	if outcome.Err != context.Canceled || outcome.Category != models.ErrorCancelled || len(outcome.Results) != 1 {
+		t.Fatalf("got error %v of category %q and %d results, want a cancelled result", outcome.Err, outcome.Category, len(outcome.Results))
+	}
+	result := outcome.Results[0]
+	if !result.Partial || result.Status != models.StatusCancelled {
+		t.Errorf("got status %q, partial %v, want a partial cancelled result", result.Status, result.Partial)
+	}
+	if result.TotalBytes == 0 || result.TotalBytes >= int64(len(data)) {
+		t.Fatalf("scanned %d of %d bytes, want part of the file", result.TotalBytes, len(data))
+	}
+
+	// The chunks before the cancellation were scanned whole and the one it
+	// interrupted up to a line, so the result is that of the lines scanned
+	if err := os.WriteFile(filepath.Join(dir, "head.log"), data[:result.TotalBytes], 0644); err != nil {
+		t.Fatal(err)
+	}
+	head, err := p.ProcessFiles(context.Background(), []string{"head.log"}, JobOptions{}, nil, func(string, int, string, error) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := head[0].Results[0]
+	want.FilePath, want.Partial, want.Status = result.FilePath, true, models.StatusCancelled
+	if resultsJSON(t, []models.Result{result}) != resultsJSON(t, []models.Result{want}) {
+		t.Errorf("partial result differs from a scan of the lines scanned:\ngot  %s\nwant %s", resultsJSON(t, []models.Result{result}), resultsJSON(t, []models.Result{want}))
+	}
+}
@@ -0,0 +1,351 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Log levels reported by the classifiers
+const (
+	LevelDebug = "DEBUG"
+	LevelInfo  = "INFO"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+	LevelFatal = "FATAL"
+)
+
+// Names of the built-in classifiers
+const (
+	ClassifierLevelToken = "level-token"
+	ClassifierWord       = "word"
+	ClassifierRegex      = "regex"
+)
+
+// LineClassifier decides which log level a single line belongs to
+type LineClassifier interface {
+	// Name returns the identifier of the classifier
+	Name() string
+	// Rules describes the rules the classifier applies
+	Rules() []string
+	// Classify returns the level of the line, or an empty string if it has none
+	Classify(line []byte) string
+}
+
+// NewClassifier creates one of the built-in classifiers by name
+func NewClassifier(name string, levelField int, rulesFile string) (LineClassifier, error) {
+	switch name {
+	case ClassifierLevelToken:
+		return NewLevelTokenClassifier(levelField), nil
+	case ClassifierWord, "":
+		return NewWordClassifier(), nil
+	case ClassifierRegex:
+		return LoadRegexClassifier(rulesFile)
+	default:
+		return nil, fmt.Errorf("unknown classifier %q", name)
+	}
+}
+
+// levelTokens maps accepted level tokens, including common aliases, to their level
+var levelTokens = map[string]string{
+	LevelDebug: LevelDebug,
+	LevelInfo:  LevelInfo,
+	LevelWarn:  LevelWarn,
+	LevelError: LevelError,
+	LevelFatal: LevelFatal,
+	"TRACE":    LevelDebug,
+	"WARNING":  LevelWarn,
+	"CRITICAL": LevelFatal,
+}
+
+// levelKey is a level token packed into an integer by packToken
+type levelKey struct {
+	key   uint64
+	level string
+}
+
+// levelKeys holds the packed level tokens, so a token is matched against all
+// of them with a few integer comparisons
+var levelKeys = func() []levelKey {
+	keys := make([]levelKey, 0, len(levelTokens))
+	for token, level := range levelTokens {
+		key, _ := packToken([]byte(token))
+		keys = append(keys, levelKey{key: key, level: level})
+	}
+	return keys
+}()
+
+// levelStart marks the bytes a level token can start with
+var levelStart = func() (table [256]bool) {
+	for token := range levelTokens {
+		table[token[0]] = true
+		table[token[0]+'a'-'A'] = true
+	}
+	return table
+}()
+
+// letters marks ASCII letters, spaces marks field separators
+var letters, spaces = func() (letters, spaces [256]bool) {
+	for b := 'a'; b <= 'z'; b++ {
+		letters[b] = true
+		letters[b-'a'+'A'] = true
+	}
+	spaces[' '], spaces['\t'], spaces['\r'] = true, true, true
+	return letters, spaces
+}()
+
+// packToken packs the upper-cased bytes of a token of up to 8 bytes into an integer
+func packToken(token []byte) (uint64, bool) {
+	if len(token) == 0 || len(token) > 8 {
+		return 0, false
+	}
+	var key uint64
+	for _, b := range token {
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		key = key<<8 | uint64(b)
+	}
+	return key, true
+}
+
+// lookupLevel maps a level token such as "error" or "WARNING" to its level name
+func lookupLevel(token []byte) string {
+	if len(token) < 4 || !levelStart[token[0]] {
+		return ""
+	}
+	key, ok := packToken(token)
+	if !ok {
+		return ""
+	}
+	for _, k := range levelKeys {
+		if k.key == key {
+			return k.level
+		}
+	}
+	return ""
+}
+
+// levelNames maps the lower-cased level names of logrus, zap, log4j and
+// syslog to their level
+var levelNames = map[string]string{
+	"trace":         LevelDebug,
+	"debug":         LevelDebug,
+	"info":          LevelInfo,
+	"informational": LevelInfo,
+	"notice":        LevelInfo,
+	"warn":          LevelWarn,
+	"warning":       LevelWarn,
+	"err":           LevelError,
+	"error":         LevelError,
+	"dpanic":        LevelError,
+	"panic":         LevelFatal,
+	"fatal":         LevelFatal,
+	"crit":          LevelFatal,
+	"critical":      LevelFatal,
+	"alert":         LevelFatal,
+	"emerg":         LevelFatal,
+	"emergency":     LevelFatal,
+}
+
+// syslogSeverities maps syslog severities 0 (emergency) to 7 (debug) to their level
+var syslogSeverities = [8]string{LevelFatal, LevelFatal, LevelFatal, LevelError, LevelWarn, LevelInfo, LevelInfo, LevelDebug}
+
+// normalizeLevel maps the level of a structured entry to its level name. It
+// accepts the names in levelNames in any case, syslog severities 0 to 7 and
+// the numeric levels of bunyan and pino (10 trace to 60 fatal), and returns
+// an empty string for other values.
+func normalizeLevel(value string) string {
+	value = strings.TrimSpace(value)
+	if level, ok := levelNames[strings.ToLower(value)]; ok {
+		return level
+	}
+
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil || n < 0:
+		return ""
+	case n < len(syslogSeverities):
+		return syslogSeverities[n]
+	case n < 10:
+		return ""
+	case n < 30:
+		return LevelDebug
+	case n < 40:
+		return LevelInfo
+	case n < 50:
+		return LevelWarn
+	case n < 60:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// LevelTokenClassifier reads the level from a fixed whitespace separated field,
+// e.g. field 1 of "[2025-01-02T15:04:05Z] ERROR message"
+type LevelTokenClassifier struct {
+	field int
+}
+
+// NewLevelTokenClassifier creates a classifier that looks at the given field (0 based)
+func NewLevelTokenClassifier(field int) *LevelTokenClassifier {
+	return &LevelTokenClassifier{field: field}
+}
+
+func (c *LevelTokenClassifier) Name() string {
+	return ClassifierLevelToken
+}
+
+func (c *LevelTokenClassifier) Rules() []string {
+	return []string{"level token at field " + strconv.Itoa(c.field)}
+}
+
+func (c *LevelTokenClassifier) Classify(line []byte) string {
+	field := 0
+	i := 0
+	for i < len(line) {
+		// Skip separators
+		for i < len(line) && spaces[line[i]] {
+			i++
+		}
+		start := i
+		for i < len(line) && !spaces[line[i]] {
+			i++
+		}
+		if start == i {
+			break
+		}
+		if field == c.field {
+			return lookupLevel(trimLevelToken(line[start:i]))
+		}
+		field++
+	}
+	return ""
+}
+
+// trimLevelToken strips brackets and a trailing colon, as in "[ERROR]" or "WARN:"
+func trimLevelToken(token []byte) []byte {
+	for len(token) > 0 && (token[0] == '[' || token[0] == '(') {
+		token = token[1:]
+	}
+	for len(token) > 0 && (token[len(token)-1] == ']' || token[len(token)-1] == ')' || token[len(token)-1] == ':') {
+		token = token[:len(token)-1]
+	}
+	return token
+}
+
+// WordClassifier finds the first level name that appears as a whole word,
+// ignoring case, so "ERRORS" or "no errors found" are not counted
+type WordClassifier struct{}
+
+// NewWordClassifier creates a case-insensitive word boundary classifier
+func NewWordClassifier() *WordClassifier {
+	return &WordClassifier{}
+}
+
+func (c *WordClassifier) Name() string {
+	return ClassifierWord
+}
+
+func (c *WordClassifier) Rules() []string {
+	return []string{"first whole word matching a level name, case-insensitive"}
+}
+
+func (c *WordClassifier) Classify(line []byte) string {
+	i := 0
+	for i < len(line) {
+		for i < len(line) && !letters[line[i]] {
+			i++
+		}
+		start := i
+		for i < len(line) && letters[line[i]] {
+			i++
+		}
+		// Words of 4 to 8 letters starting like a level name are the only candidates
+		if n := i - start; n >= 4 && n <= 8 && levelStart[line[start]] {
+			if level := lookupLevel(line[start:i]); level != "" {
+				return level
+			}
+		}
+	}
+	return ""
+}
+
+// RegexRule assigns a level to lines matching a pattern
+type RegexRule struct {
+	Level   string `json:"level"`
+	Pattern string `json:"pattern"`
+}
+
+// RegexClassifier applies an ordered list of regex rules; the first match wins
+type RegexClassifier struct {
+	rules    []RegexRule
+	patterns []*regexp.Regexp
+}
+
+// NewRegexClassifier compiles the given rules
+func NewRegexClassifier(rules []RegexRule) (*RegexClassifier, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("regex classifier needs at least one rule")
+	}
+
+	c := &RegexClassifier{}
+	for _, rule := range rules {
+		level := lookupLevel([]byte(rule.Level))
+		if level == "" {
+			return nil, fmt.Errorf("unknown level %q in rule %q", rule.Level, rule.Pattern)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", rule.Pattern, err)
+		}
+		c.rules = append(c.rules, RegexRule{Level: level, Pattern: rule.Pattern})
+		c.patterns = append(c.patterns, pattern)
+	}
+	return c, nil
+}
+
+// LoadRegexClassifier reads the rules from a JSON file of the form
+// [{"level": "ERROR", "pattern": "\\bERROR\\b"}, ...]
+func LoadRegexClassifier(rulesFile string) (*RegexClassifier, error) {
+	if rulesFile == "" {
+		return nil, fmt.Errorf("regex classifier needs a rules file")
+	}
+
+	data, err := os.ReadFile(rulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file %s: %v", rulesFile, err)
+	}
+
+	var rules []RegexRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing rules file %s: %v", rulesFile, err)
+	}
+
+	return NewRegexClassifier(rules)
+}
+
+func (c *RegexClassifier) Name() string {
+	return ClassifierRegex
+}
+
+func (c *RegexClassifier) Rules() []string {
+	rules := make([]string, len(c.rules))
+	for i, rule := range c.rules {
+		rules[i] = fmt.Sprintf("%s: %s", rule.Level, rule.Pattern)
+	}
+	return rules
+}
+
+func (c *RegexClassifier) Classify(line []byte) string {
+	for i, pattern := range c.patterns {
+		if pattern.Match(line) {
+			return c.rules[i].Level
+		}
+	}
+	return ""
+}
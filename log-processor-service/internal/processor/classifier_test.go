@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWordClassifier(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"[2025-04-01T10:00:00Z] ERROR Invalid request format detected", LevelError},
+		{"[2025-04-01T10:00:00Z] WARN retrying after ERROR from upstream", LevelWarn},
+		{"2025-04-01 10:00:00 error: disk full", LevelError},
+		{"Warning: low memory", LevelWarn},
+		{"level=critical msg=down", LevelFatal},
+		{"TRACE entering handler", LevelDebug},
+		{"[INFO] started", LevelInfo},
+		{"check finished, no errors found", ""},
+		{"ERRORS: 0", ""},
+		{"the information desk", ""},
+		{"errorless run", ""},
+		{"", ""},
+	}
+	c := NewWordClassifier()
+	for _, tt := range tests {
+		if got := c.Classify([]byte(tt.line)); got != tt.want {
+			t.Errorf("Classify(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLevelTokenClassifier(t *testing.T) {
+	tests := []struct {
+		field int
+		line  string
+		want  string
+	}{
+		{1, "[2025-04-01T10:00:00Z] ERROR failed", LevelError},
+		{1, "[2025-04-01T10:00:00Z]   WARN: slow", LevelWarn},
+		{1, "[2025-04-01T10:00:00Z] [FATAL] down", LevelFatal},
+		{1, "[2025-04-01T10:00:00Z] (warning) slow", LevelWarn},
+		{1, "[2025-04-01T10:00:00Z] error failed", LevelError},
+		{0, "INFO\tstarted", LevelInfo},
+		{2, "2025-04-01 10:00:00 TRACE enter", LevelDebug},
+		{1, "[2025-04-01T10:00:00Z] request ERROR failed", ""},
+		{1, "[2025-04-01T10:00:00Z] ERRORS 3", ""},
+		{3, "too few fields", ""},
+	}
+	for _, tt := range tests {
+		if got := NewLevelTokenClassifier(tt.field).Classify([]byte(tt.line)); got != tt.want {
+			t.Errorf("field %d: Classify(%q) = %q, want %q", tt.field, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRegexClassifier(t *testing.T) {
+	c, err := NewRegexClassifier([]RegexRule{
+		{Level: "fatal", Pattern: `(?i)out of memory`},
+		{Level: "ERROR", Pattern: `\bERROR\b|status=5\d\d`},
+		{Level: "warning", Pattern: `\bWARN`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"ERROR request failed", LevelError},
+		{"GET /api status=503", LevelError},
+		{"WARN slow request", LevelWarn},
+		{"ERROR: Out Of Memory", LevelFatal},
+		{"WARN then ERROR", LevelError},
+		{"INFO started", ""},
+	}
+	for _, tt := range tests {
+		if got := c.Classify([]byte(tt.line)); got != tt.want {
+			t.Errorf("Classify(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	want := []string{"FATAL: (?i)out of memory", `ERROR: \bERROR\b|status=5\d\d`, `WARN: \bWARN`}
+	if got := c.Rules(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Rules() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadRegexClassifier(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{name: "rules", rules: `[{"level": "ERROR", "pattern": "\\bERROR\\b"}, {"level": "WARN", "pattern": "WARN"}]`},
+		{name: "malformed", rules: `[{"level": "ERROR", "pattern": }]`, err: "error parsing rules file"},
+		{name: "not a list", rules: `{"level": "ERROR", "pattern": "ERROR"}`, err: "error parsing rules file"},
+		{name: "no rules", rules: `[]`, err: "needs at least one rule"},
+		{name: "unknown level", rules: `[{"level": "LOUD", "pattern": "!"}]`, err: `unknown level "LOUD"`},
+		{name: "invalid pattern", rules: `[{"level": "ERROR", "pattern": "(ERROR"}]`, err: `invalid pattern "(ERROR"`},
+		{name: "missing file", err: "error reading rules file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if tt.rules != "" {
+				if err := os.WriteFile(path, []byte(tt.rules), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c, err := LoadRegexClassifier(path)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := c.Classify([]byte("ERROR and WARN")); got != LevelError {
+					t.Errorf("Classify = %q, want %q", got, LevelError)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+	if _, err := LoadRegexClassifier(""); err == nil {
+		t.Error("loaded a classifier without a rules file")
+	}
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats recognised by their magic bytes
+const (
+	CompressionGzip  = "gzip"
+	CompressionZstd  = "zstd"
+	CompressionBzip2 = "bzip2"
+)
+
+// errExpandsTooLarge is returned for compressed files that expand beyond the
+// size limit or the compression ratio limit
+var errExpandsTooLarge = errors.New("compressed file expands beyond the size limit")
+
+var compressionMagic = []struct {
+	compression string
+	magic       []byte
+}{
+	{CompressionGzip, []byte{0x1f, 0x8b}},
+	{CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{CompressionBzip2, []byte("BZh")},
+}
+
+// detectCompression returns the compression format of a file from its first
+// bytes, or an empty string for plain files
+func detectCompression(r io.ReaderAt) (string, error) {
+	header := make([]byte, 4)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return compressionOf(header[:n]), nil
+}
+
+// compressionOf returns the compression format a stream starting with header is in
+func compressionOf(header []byte) string {
+	for _, format := range compressionMagic {
+		if bytes.HasPrefix(header, format.magic) {
+			return format.compression
+		}
+	}
+	return ""
+}
+
+// newDecompressor wraps r so it yields the decompressed stream. Corrupt streams
+// fail with a decodeError, errors reading r are returned as they are.
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	dr := &decodeReader{source: &sourceReader{r: r}}
+	var reader io.ReadCloser
+	switch compression {
+	case CompressionGzip:
+		gzipReader, err := gzip.NewReader(dr.source)
+		if err != nil {
+			return nil, dr.mark(fmt.Errorf("error opening gzip stream: %w", err))
+		}
+		reader = gzipReader
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(dr.source)
+		if err != nil {
+			return nil, dr.mark(fmt.Errorf("error opening zstd stream: %w", err))
+		}
+		reader = decoder.IOReadCloser()
+	case CompressionBzip2:
+		reader = io.NopCloser(bzip2.NewReader(dr.source))
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+
+	dr.r = reader
+	return struct {
+		io.Reader
+		io.Closer
+	}{dr, reader}, nil
+}
+
+// countingReader reports the bytes read from the underlying compressed file as
+// progress, so the percentage follows the compressed size
+type countingReader struct {
+	r        io.Reader
+	progress *fileProgress
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.progress.advance(int64(n))
+	return n, err
+}
+
+// expansionReader fails with errExpandsTooLarge once a compressed file
+// expanded to more bytes than allowed
+type expansionReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// limitExpansion bounds the decompressed stream of a file of the given
+// compressed size by the limits that apply to archives
+func (p *Processor) limitExpansion(r io.Reader, size int64) io.Reader {
+	// Expanding to more than the compression ratio allows is treated as a bomb
+	remaining := p.maxArchiveBytes
+	if p.maxCompressionRatio > 0 {
+		remaining = min(remaining, size*p.maxCompressionRatio)
+	}
+	return &expansionReader{r: r, remaining: remaining}
+}
+
+func (er *expansionReader) Read(p []byte) (int, error) {
+	if er.remaining < 0 {
+		return 0, errExpandsTooLarge
+	}
+	if int64(len(p)) > er.remaining+1 {
+		p = p[:er.remaining+1]
+	}
+	n, err := er.r.Read(p)
+	er.remaining -= int64(n)
+	if er.remaining < 0 {
+		return n, errExpandsTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestCompressedFileLimits(t *testing.T) {
+	plain := generatorLog(64 * 1024)
+	repeated := []byte(strings.Repeat("[2025-04-01T10:00:00Z] INFO same line again\n", 4096))
+
+	tests := []struct {
+		name   string
+		data   []byte
+		limits func(cfg *config.Config)
+		err    bool
+	}{
+		{name: "within the limits", data: plain, limits: func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) }},
+		{name: "beyond the size limit", data: plain, limits: func(cfg *config.Config) { cfg.MaxArchiveBytes = int64(len(plain)) - 1 }, err: true},
+		{name: "beyond the compression ratio limit", data: repeated, limits: func(cfg *config.Config) { cfg.MaxCompressionRatio = 10 }, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "app.log.gz"), gzipBytes(t, tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.NewConfig()
+			cfg.UploadDir = dir
+			cfg.MaxCompressionRatio = 0
+			tt.limits(cfg)
+			p, err := NewProcessor(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			outcomes, err := p.ProcessFiles(context.Background(), []string{"app.log.gz"}, JobOptions{}, nil, func(string, int, string, error) {}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outcome := outcomes[0]
+			if !tt.err {
+				if outcome.Err != nil || outcome.Results[0].TotalBytes != int64(len(tt.data)) {
+					t.Fatalf("got error %v, want the whole file scanned", outcome.Err)
+				}
+				return
+			}
+			if !errors.Is(outcome.Err, errExpandsTooLarge) || outcome.Category != models.ErrorLimit {
+				t.Fatalf("got error %v of category %q, want %v", outcome.Err, outcome.Category, errExpandsTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// errUnknownColumn is a mapped column missing from the header of a CSV file
+var errUnknownColumn = errors.New("column not in CSV header")
+
+// Header names the columns of a CSV log are detected by, most specific first
+var (
+	timestampColumns = []string{"timestamp", "@timestamp", "time", "ts", "datetime", "date", "time_stamp", "created_at", "logged_at"}
+	levelColumns     = []string{"level", "severity", "loglevel", "log_level", "lvl", "priority"}
+	messageColumns   = []string{"message", "msg", "@message", "text", "log", "description", "event"}
+)
+
+// timestampLayouts are the timestamp formats accepted in CSV columns besides
+// RFC 3339 and Unix times in seconds or milliseconds
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// csvLayout is the columns of a CSV file, resolved from its header. Columns
+// that are missing have index -1.
+type csvLayout struct {
+	mapping   models.ColumnMapping
+	header    []string
+	timestamp int
+	level     int
+	message   int
+}
+
+func newCSVLayout(mapping models.ColumnMapping) *csvLayout {
+	return &csvLayout{mapping: mapping, timestamp: -1, level: -1, message: -1}
+}
+
+// setHeader resolves the columns from the header record. Mapped columns must
+// be in the header, the others are detected by their name.
+func (l *csvLayout) setHeader(fields [][]byte) error {
+	l.header = make([]string, len(fields))
+	for i, field := range fields {
+		l.header[i] = strings.TrimSpace(string(field))
+	}
+	if len(l.header) > 0 {
+		l.header[0] = strings.TrimPrefix(l.header[0], "\ufeff")
+	}
+
+	var err error
+	if l.timestamp, err = l.column(l.mapping.Timestamp, timestampColumns); err != nil {
+		return err
+	}
+	if l.level, err = l.column(l.mapping.Level, levelColumns); err != nil {
+		return err
+	}
+	l.message, err = l.column(l.mapping.Message, messageColumns)
+	return err
+}
+
+// column returns the index of the mapped column, or of the first candidate
+// found if none is mapped
+func (l *csvLayout) column(mapped string, candidates []string) (int, error) {
+	if mapped != "" {
+		if i := l.index(mapped); i >= 0 {
+			return i, nil
+		}
+		return -1, fmt.Errorf("%w: %q", errUnknownColumn, mapped)
+	}
+	for _, candidate := range candidates {
+		if i := l.index(candidate); i >= 0 {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func (l *csvLayout) index(name string) int {
+	for i, column := range l.header {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columns returns the names of the columns entries are read from
+func (l *csvLayout) columns() *models.ColumnMapping {
+	name := func(i int) string {
+		if i < 0 {
+			return ""
+		}
+		return l.header[i]
+	}
+	return &models.ColumnMapping{
+		Timestamp: name(l.timestamp),
+		Level:     name(l.level),
+		Message:   name(l.message),
+	}
+}
+
+// field returns the value of a column of a record, or nil if it is missing
+func field(fields [][]byte, i int) []byte {
+	if i < 0 || i >= len(fields) {
+		return nil
+	}
+	return fields[i]
+}
+
+// observeRecord analyses a CSV record, the first of which is the header.
+// Records span several lines when quoted fields contain newlines; a stack
+// trace in the message is parsed like the continuation lines of text logs.
+func (s *scanState) observeRecord(r *csvReader) error {
+	line := s.lines + 1
+	s.lines += r.lines
+	result := &s.result
+	s.index.Add(line, s.offset+result.TotalBytes, r.record)
+	result.TotalLines += r.lines
+	result.LongestLine = max(result.LongestLine, r.longest)
+	if r.truncated {
+		result.OversizedLines++
+	}
+
+	fields := r.fields
+	if s.csv.header == nil {
+		return s.csv.setHeader(fields)
+	}
+	if len(fields) == 1 && len(bytes.TrimSpace(fields[0])) == 0 {
+		return nil
+	}
+
+	var level string
+	if s.csv.level >= 0 {
+		value := field(fields, s.csv.level)
+		if level = normalizeLevel(string(value)); level == "" {
+			level = s.classifier.Classify(value)
+		}
+	} else {
+		level = s.classifier.Classify(r.record)
+	}
+
+	message := string(r.record)
+	if s.csv.message >= 0 {
+		message = string(field(fields, s.csv.message))
+	}
+	text := message
+	if end := strings.IndexByte(message, '\n'); end >= 0 {
+		message = message[:end]
+	}
+
+	entry, err := s.csv.entry(fields, level, message, s.fields != nil || s.filter != nil)
+	var parsed *models.LogEntry
+	if err == nil {
+		parsed = &entry
+	}
+	if excluded := s.filter.exclude(parsed, level, r.record); excluded != "" {
+		result.AddExcluded(excluded, r.lines)
+		return nil
+	}
+
+	result.Entries++
+	result.ContinuationLines += r.lines - 1
+	s.countLevel(level)
+	if text != message {
+		if trace, ok := parseStackTrace(strings.Split(text, "\n")); ok {
+			s.exceptions.Add(trace, level)
+		}
+	}
+	if err != nil {
+		s.malformed(line, err)
+	} else if !entry.Timestamp.IsZero() {
+		result.AddTimestamp(entry.Timestamp)
+	}
+
+	s.fields.Add(parsed, level)
+
+	if level == "" {
+		return nil
+	}
+	s.samples.Add(level, line, s.offset+result.TotalBytes, r.record)
+	if parsed != nil && !entry.Timestamp.IsZero() {
+		s.timeline.Add(entry.Timestamp, level)
+	}
+	s.templates.Add(level, &models.LogEntry{Message: message}, r.record)
+	return nil
+}
+
+// entry builds the log entry of a record. The columns other than timestamp,
+// level and message make up its payload when withPayload is set.
+func (l *csvLayout) entry(fields [][]byte, level, message string, withPayload bool) (models.LogEntry, error) {
+	entry := models.LogEntry{Level: level, Message: message}
+	if len(fields) != len(l.header) {
+		return entry, fmt.Errorf("expected %d fields, got %d", len(l.header), len(fields))
+	}
+
+	if l.timestamp >= 0 {
+		timestamp, err := parseTimestampValue(strings.TrimSpace(string(fields[l.timestamp])))
+		if err != nil {
+			return entry, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		entry.Timestamp = timestamp
+	}
+
+	if withPayload {
+		entry.Payload = make(map[string]any, len(fields))
+		for i, value := range fields {
+			if i != l.timestamp && i != l.level && i != l.message && l.header[i] != "" {
+				entry.Payload[l.header[i]] = string(value)
+			}
+		}
+	}
+	return entry, nil
+}
+
+// parseTimestampValue parses a timestamp in one of the usual formats
+func parseTimestampValue(value string) (time.Time, error) {
+	if timestamp, err := parseTimestamp([]byte(value)); err == nil {
+		return timestamp, nil
+	}
+	for _, layout := range timestampLayouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp, nil
+		}
+	}
+	if unix, err := strconv.ParseFloat(value, 64); err == nil && unix > 0 {
+		if unix >= 1e12 {
+			return time.UnixMilli(int64(unix)).UTC(), nil
+		}
+		return time.Unix(0, int64(unix*float64(time.Second))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// csvReader reads the records of a CSV stream. Quoted fields may contain
+// commas, doubled quotes and newlines, so a record can span several lines.
+// Records are truncated to maxRecord bytes; like lineReader it reuses its
+// buffers, so a record is only valid until the next call to Next.
+type csvReader struct {
+	reader    *lineReader
+	maxRecord int
+
+	record    []byte   // raw bytes of the record, lines joined by '\n'
+	fields    [][]byte // unquoted fields, pointing into buf
+	buf       []byte
+	bounds    []int
+	lines     int  // number of lines of the record
+	longest   int  // full length of its longest line
+	truncated bool // whether any of its lines or the record was cut off
+}
+
+func newCSVReader(r io.Reader, maxRecord int) *csvReader {
+	return &csvReader{reader: newLineReader(r, maxRecord), maxRecord: maxRecord}
+}
+
+// Next reads the next record and returns the number of bytes it occupied in
+// the stream. It returns io.EOF once the stream is exhausted; a quoted field
+// left open at the end of the stream ends the record.
+func (r *csvReader) Next() (int, error) {
+	r.record = r.record[:0]
+	r.lines, r.longest, r.truncated = 0, 0, false
+	consumed := 0
+	quoted := false
+	for {
+		line, n, err := r.reader.Next()
+		if err == io.EOF && r.lines > 0 {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		consumed += n
+		length := r.reader.Length()
+		r.longest = max(r.longest, length)
+		r.truncated = r.truncated || length > len(line)
+
+		if r.lines > 0 {
+			r.append(newline)
+		}
+		r.append(line)
+		r.lines++
+
+		// Doubled quotes cancel out, an odd count opens or closes a field
+		if bytes.Count(line, quote)%2 == 1 {
+			quoted = !quoted
+		}
+		if !quoted {
+			break
+		}
+	}
+	r.split()
+	return consumed, nil
+}
+
+var quote, newline = []byte{'"'}, []byte{'\n'}
+
+// append adds to the record as much of b as fits
+func (r *csvReader) append(b []byte) {
+	if room := r.maxRecord - len(r.record); room < len(b) {
+		b = b[:max(room, 0)]
+		r.truncated = true
+	}
+	r.record = append(r.record, b...)
+}
+
+// split unquotes the fields of the record. Quotes within unquoted fields and
+// text after the closing quote of a field are kept as they are.
+func (r *csvReader) split() {
+	record := r.record
+	r.buf = r.buf[:0]
+	r.bounds = r.bounds[:0]
+	for i := 0; ; i++ {
+		if i < len(record) && record[i] == '"' {
+			for i++; i < len(record); i++ {
+				if record[i] == '"' {
+					if i+1 < len(record) && record[i+1] == '"' {
+						i++
+					} else {
+						i++
+						break
+					}
+				}
+				r.buf = append(r.buf, record[i])
+			}
+		}
+		for ; i < len(record) && record[i] != ','; i++ {
+			r.buf = append(r.buf, record[i])
+		}
+		r.bounds = append(r.bounds, len(r.buf))
+		if i >= len(record) {
+			break
+		}
+	}
+
+	r.fields = r.fields[:0]
+	start := 0
+	for _, end := range r.bounds {
+		r.fields = append(r.fields, r.buf[start:end])
+		start = end
+	}
+}
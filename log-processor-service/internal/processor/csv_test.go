@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestCSVReaderRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+		lines []int
+	}{
+		{
+			name:  "plain fields",
+			input: "timestamp,level,message\n2024-01-01T00:00:00Z,INFO,started\n",
+			want:  [][]string{{"timestamp", "level", "message"}, {"2024-01-01T00:00:00Z", "INFO", "started"}},
+			lines: []int{1, 1},
+		},
+		{
+			name:  "quoted commas",
+			input: "a,\"b, c\",d\n",
+			want:  [][]string{{"a", "b, c", "d"}},
+			lines: []int{1},
+		},
+		{
+			name:  "doubled quotes",
+			input: "\"say \"\"hi\"\"\",\"\"\"\",x\n",
+			want:  [][]string{{`say "hi"`, `"`, "x"}},
+			lines: []int{1},
+		},
+		{
+			name:  "empty fields",
+			input: ",,\r\n\"\",x,\n",
+			want:  [][]string{{"", "", ""}, {"", "x", ""}},
+			lines: []int{1, 1},
+		},
+		{
+			name:  "multi-line field",
+			input: "ERROR,\"failed\n  at main.go:10\n  at run.go:5\",x\nINFO,ok,y\n",
+			want:  [][]string{{"ERROR", "failed\n  at main.go:10\n  at run.go:5", "x"}, {"INFO", "ok", "y"}},
+			lines: []int{3, 1},
+		},
+		{
+			name:  "quotes inside an unquoted field",
+			input: "a,b \"c\" d,e\n",
+			want:  [][]string{{"a", `b "c" d`, "e"}},
+			lines: []int{1},
+		},
+		{
+			name:  "quoted field left open",
+			input: "a,\"b\nc",
+			want:  [][]string{{"a", "b\nc"}},
+			lines: []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newCSVReader(strings.NewReader(tt.input), defaultMaxLineLength)
+			var got [][]string
+			var lines []int
+			consumed := 0
+			for {
+				n, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				consumed += n
+				record := make([]string, len(reader.fields))
+				for i, field := range reader.fields {
+					record[i] = string(field)
+				}
+				got = append(got, record)
+				lines = append(lines, reader.lines)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got records %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("got lines %v, want %v", lines, tt.lines)
+			}
+			if consumed != len(tt.input) {
+				t.Errorf("consumed %d bytes, want %d", consumed, len(tt.input))
+			}
+		})
+	}
+}
+
+func TestCSVEntries(t *testing.T) {
+	input := "time,severity,msg,user\n" +
+		"2024-01-01 10:00:00,info,\"login, ok\",bob\n" +
+		"2024-01-01 10:00:01,error,\"Exception in thread \"\"main\"\" java.lang.IllegalStateException: boom\n" +
+		"\tat com.example.App.run(App.java:10)\n" +
+		"\tat com.example.App.main(App.java:5)\",alice\n" +
+		"2024-01-01 10:00:02,warn,missing field\n"
+
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := p.newFormatState(FormatCSV, models.ColumnMapping{}, nil)
+	state, err = p.scanLines(context.Background(), strings.NewReader(input), state, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := state.finish("app.csv", 10, 10)
+	if result.TotalLines != 6 || result.Entries != 3 || result.ContinuationLines != 2 {
+		t.Errorf("got %d lines, %d entries, %d continuation lines, want 6, 3, 2", result.TotalLines, result.Entries, result.ContinuationLines)
+	}
+	if want := map[string]int{LevelInfo: 1, LevelError: 1, LevelWarn: 1}; !reflect.DeepEqual(result.Levels, want) {
+		t.Errorf("got levels %v, want %v", result.Levels, want)
+	}
+	if result.MalformedLines != 1 {
+		t.Errorf("got %d malformed lines, want 1", result.MalformedLines)
+	}
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Type != "java.lang.IllegalStateException" {
+		t.Errorf("got exceptions %+v, want one java.lang.IllegalStateException", result.Exceptions)
+	}
+}
@@ -0,0 +1,293 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+const (
+	// defaultDetectLines is the number of lines sampled to detect a format
+	// when none is configured
+	defaultDetectLines = 200
+	// detectSampleEntries bounds the parsed entries returned with a detection
+	detectSampleEntries = 5
+)
+
+// errDetectArchive is returned for archives, whose members each have a
+// format of their own
+var errDetectArchive = errors.New("cannot detect the format of an archive")
+
+// formatScore counts the sampled entries a format parsed
+type formatScore struct {
+	format     string
+	parsed     int
+	total      int
+	lastFailed bool
+	sample     []models.LogEntry
+}
+
+func (s *formatScore) add(entry models.LogEntry, err error) {
+	s.total++
+	s.lastFailed = err != nil
+	if err != nil {
+		return
+	}
+	s.parsed++
+	if len(s.sample) < detectSampleEntries {
+		s.sample = append(s.sample, entry)
+	}
+}
+
+// dropCutOff leaves out the last entry if it failed, as an entry spanning
+// lines may be cut off by the end of the sample
+func (s *formatScore) dropCutOff() {
+	if s.lastFailed {
+		s.total--
+		s.lastFailed = false
+	}
+}
+
+func (s *formatScore) rate() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return float64(s.parsed) / float64(s.total)
+}
+
+// DetectFormat samples the first lines of a file and scores every format on
+// the share of them it parses, returning the best. Compressed files are
+// sampled decompressed; ties go to the format of the file extension, then to
+// the more specific format.
+func (p *Processor) DetectFormat(filePath string) (models.Detection, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return models.Detection{}, fmt.Errorf("error opening file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return models.Detection{}, fmt.Errorf("error getting file info: %w", err)
+	}
+	compression, err := detectCompression(file)
+	if err != nil {
+		return models.Detection{}, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	archive, err := detectArchive(file, info.Size(), compression)
+	if err != nil {
+		return models.Detection{}, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	if archive != "" {
+		return models.Detection{}, fmt.Errorf("%w: %s is a %s archive", errDetectArchive, filepath.Base(filePath), archive)
+	}
+
+	detection, err := p.detectStream(file, compression, formatOf(filePath))
+	if err != nil {
+		return models.Detection{}, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	detection.FileName = filepath.Base(filePath)
+	return detection, nil
+}
+
+// detectStream detects the format of a file that is not an archive from the
+// head of its stream, decompressed if it is compressed
+func (p *Processor) detectStream(file io.ReaderAt, compression, extension string) (models.Detection, error) {
+	var source io.Reader = io.NewSectionReader(file, 0, math.MaxInt64)
+	if compression != "" {
+		decompressor, err := newDecompressor(source, compression)
+		if err != nil {
+			return models.Detection{}, err
+		}
+		defer decompressor.Close()
+		source = decompressor
+	}
+
+	lines, complete, err := p.sampleLines(source)
+	if err != nil {
+		return models.Detection{}, err
+	}
+
+	detection := p.detect(lines, complete, extension)
+	detection.Compression = compression
+	return detection, nil
+}
+
+// sampleLines reads up to the detection sample size of lines, reporting
+// whether they are the whole stream
+func (p *Processor) sampleLines(source io.Reader) ([][]byte, bool, error) {
+	reader := newLineReader(source, p.maxLine)
+	var lines [][]byte
+	for len(lines) < p.detectLines {
+		line, _, err := reader.Next()
+		if err == io.EOF {
+			return lines, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		lines = append(lines, append([]byte(nil), line...))
+	}
+	_, _, err := reader.Next()
+	return lines, err == io.EOF, nil
+}
+
+// detect scores the formats on sampled lines, trying the format of the file
+// extension first. Unless the sample is the whole file, a last CSV record or
+// JSON value that fails to parse is left out, as it may be cut off.
+func (p *Processor) detect(lines [][]byte, complete bool, extension string) models.Detection {
+	formats := []string{extension}
+	for _, format := range append([]string{FormatJSON, FormatCSV}, parserNames...) {
+		if format != extension {
+			formats = append(formats, format)
+		}
+	}
+
+	detection := models.Detection{
+		Format:       FormatText,
+		SampledLines: len(lines),
+		Scores:       make(map[string]float64, len(formats)),
+	}
+	var best, runnerUp *formatScore
+	for _, format := range formats {
+		score := &formatScore{format: format}
+		switch format {
+		case FormatJSON:
+			p.scoreJSON(score, lines)
+		case FormatCSV:
+			p.scoreCSV(score, lines)
+		default:
+			p.scoreLines(score, lines)
+		}
+		if !complete && (format == FormatJSON || format == FormatCSV) {
+			score.dropCutOff()
+		}
+
+		detection.Scores[format] = round(score.rate())
+		switch {
+		case best == nil || score.rate() > best.rate():
+			best, runnerUp = score, best
+		case runnerUp == nil || score.rate() > runnerUp.rate():
+			runnerUp = score
+		}
+	}
+
+	if best.parsed == 0 {
+		return detection
+	}
+	detection.Format = best.format
+	detection.SuccessRate = round(best.rate())
+	detection.Confidence = round(best.rate() - runnerUp.rate()/2)
+	detection.Sample = best.sample
+	return detection
+}
+
+func round(rate float64) float64 {
+	return math.Round(rate*1000) / 1000
+}
+
+// scoreLines parses the non-blank lines with the parser of a line format,
+// classifying those whose format carries no level. As in a scan, lines not
+// starting an entry of the format continue the entry before them; they are
+// left out if they hold its stack trace and fail otherwise, so lines of free
+// text do not pass for entries.
+func (p *Processor) scoreLines(score *formatScore, lines [][]byte) {
+	parser := lineParsers[score.format]
+	entryStart := p.entryStartOf(score.format)
+	var pending *pendingEntry
+	finish := func() {
+		if _, ok := pending.trace(); ok || pending == nil {
+			return
+		}
+		for range pending.count {
+			score.add(models.LogEntry{}, errUnparsedContinuation)
+		}
+	}
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if entryStart != nil && pending != nil && !entryStart.Match(line) {
+			pending.add(string(line))
+			continue
+		}
+		finish()
+		if pending == nil {
+			pending = &pendingEntry{}
+		}
+		pending.reset(line, "")
+
+		entry, err := parser.Parse(line)
+		if entry.Level == "" {
+			entry.Level = p.classifier.Classify(line)
+		}
+		score.add(entry, err)
+	}
+	finish()
+}
+
+// scoreJSON parses the values of the sample as entries
+func (p *Processor) scoreJSON(score *formatScore, lines [][]byte) {
+	reader := newJSONReader(bytes.NewReader(bytes.Join(lines, newline)), p.maxLine)
+	for {
+		if _, err := reader.Next(); err != nil {
+			return
+		}
+		if len(reader.record) == 0 {
+			continue
+		}
+
+		object, err := decodePayload(reader.record)
+		if err != nil {
+			score.add(models.LogEntry{}, err)
+			continue
+		}
+		entry, err := p.jsonFields.entry(object)
+		if entry.Level == "" {
+			entry.Level = p.classifier.Classify(reader.record)
+		}
+		score.add(entry, err)
+	}
+}
+
+// scoreCSV parses the records of the sample as entries. A sample whose
+// header names none of the timestamp, level and message columns is not CSV.
+func (p *Processor) scoreCSV(score *formatScore, lines [][]byte) {
+	reader := newCSVReader(bytes.NewReader(bytes.Join(lines, newline)), p.maxLine)
+	layout := newCSVLayout(models.ColumnMapping{})
+	for {
+		if _, err := reader.Next(); err != nil {
+			return
+		}
+		fields := reader.fields
+		if layout.header == nil {
+			layout.setHeader(fields)
+			if len(fields) < 2 || (layout.timestamp < 0 && layout.level < 0 && layout.message < 0) {
+				return
+			}
+			continue
+		}
+		if len(fields) == 1 && len(bytes.TrimSpace(fields[0])) == 0 {
+			continue
+		}
+
+		var level string
+		if layout.level >= 0 {
+			level = normalizeLevel(string(field(fields, layout.level)))
+		}
+		if level == "" {
+			level = p.classifier.Classify(reader.record)
+		}
+		message := string(reader.record)
+		if layout.message >= 0 {
+			message = string(field(fields, layout.message))
+		}
+		score.add(layout.entry(fields, level, message, true))
+	}
+}
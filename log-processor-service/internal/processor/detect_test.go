@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		extension  string
+		head       string
+		format     string
+		success    float64
+		confidence float64
+	}{
+		{
+			name:   "generator text",
+			head:   "[2025-04-01T10:00:00Z] INFO started {\"port\":80}\n[2025-04-01T10:00:01Z] ERROR failed\n",
+			format: FormatText, success: 1, confidence: 1,
+		},
+		{
+			name: "generator text with a stack trace",
+			head: "[2025-04-01T10:00:00Z] ERROR failed\n" +
+				"java.lang.IllegalStateException: boom\n\tat com.example.App.run(App.java:10)\n" +
+				"[2025-04-01T10:00:01Z] INFO recovered\n",
+			format: FormatText, success: 1, confidence: 1,
+		},
+		{
+			name:      "csv",
+			extension: FormatCSV,
+			head:      "timestamp,level,message\n2025-04-01T10:00:00Z,INFO,started\n2025-04-01T10:00:01Z,ERROR,failed\n",
+			format:    FormatCSV, success: 1, confidence: 1,
+		},
+		{
+			name:   "json lines",
+			head:   "{\"level\":\"info\",\"msg\":\"started\"}\n{\"level\":\"error\",\"msg\":\"failed\"}\n",
+			format: FormatJSON, success: 1, confidence: 1,
+		},
+		{
+			name:   "json array",
+			head:   "[\n  {\"level\": \"info\", \"msg\": \"started\"},\n  {\"level\": \"error\", \"msg\": \"failed\"}\n]\n",
+			format: FormatJSON, success: 1, confidence: 1,
+		},
+		{
+			name: "access",
+			head: "127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] \"GET / HTTP/1.0\" 200 2326\n" +
+				"127.0.0.1 - - [10/Oct/2000:13:55:37 -0700] \"GET /a HTTP/1.0\" 404 12 \"-\" \"curl/8.0\"\n",
+			format: FormatAccess, success: 1, confidence: 1,
+		},
+		{
+			name: "syslog",
+			head: "<34>Oct 11 22:14:15 host su[230]: 'su root' failed\n" +
+				"<165>1 2003-10-11T22:14:15.003Z host app - - - started\n" +
+				"2025-04-01T10:00:00+00:00 host sshd[99]: accepted\n",
+			format: FormatSyslog, success: 1, confidence: 1,
+		},
+		{
+			name:   "logfmt",
+			head:   "time=2025-04-01T10:00:00Z level=info msg=started\ntime=2025-04-01T10:00:01Z level=error msg=\"failed hard\"\n",
+			format: FormatLogfmt, success: 1, confidence: 1,
+		},
+		{
+			name: "mixed head",
+			head: "[2025-04-01T10:00:00Z] INFO started\n" +
+				"the quick brown fox\njumps over\nthe lazy dog\nand runs away\n",
+			format: FormatText, success: 0.2, confidence: 0.2,
+		},
+		{
+			name: "ambiguous head",
+			head: "<34>Oct 11 22:14:15 host su: failed\n" +
+				"time=2025-04-01T10:00:00Z level=info msg=started\n" +
+				"<34>Oct 11 22:14:16 host su: failed\n" +
+				"time=2025-04-01T10:00:01Z level=info msg=started\n",
+			format: FormatSyslog, success: 0.5, confidence: 0.25,
+		},
+		{
+			name:   "bare RFC 3339 timestamps",
+			head:   "2025-04-01T10:00:00Z ERROR something happened\n\tat com.example.App.run(App.java:10)\n2025-04-01T10:00:01Z INFO all good\n",
+			format: FormatText,
+		},
+		{
+			name:   "no recognized format",
+			head:   "Lorem ipsum dolor sit amet,\nconsectetur adipiscing elit,\nsed do eiusmod tempor.\n",
+			format: FormatText,
+		},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extension := tt.extension
+			if extension == "" {
+				extension = FormatText
+			}
+			var lines [][]byte
+			for _, line := range strings.SplitAfter(tt.head, "\n") {
+				if line != "" {
+					lines = append(lines, []byte(strings.TrimSuffix(line, "\n")))
+				}
+			}
+			detection := p.detect(lines, true, extension)
+			if detection.Format != tt.format || detection.SuccessRate != tt.success || detection.Confidence != tt.confidence {
+				t.Errorf("detected %s with success rate %v and confidence %v, want %s, %v, %v (scores %v)",
+					detection.Format, detection.SuccessRate, detection.Confidence, tt.format, tt.success, tt.confidence, detection.Scores)
+			}
+			if tt.success > 0 && len(detection.Sample) == 0 {
+				t.Error("detection has no sample entries")
+			}
+		})
+	}
+}
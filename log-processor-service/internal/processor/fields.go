@@ -0,0 +1,259 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+const (
+	// allFields aggregates every top level payload key
+	allFields = "*"
+	// maxFieldValues bounds the distinct values tracked per field
+	maxFieldValues = 100000
+	// maxFields bounds the number of fields tracked when aggregating all keys
+	maxFields = 50
+)
+
+// fieldCounter tracks the values of a single payload field
+type fieldCounter struct {
+	count     int
+	truncated bool
+	values    map[string]*models.FieldValue
+}
+
+// fieldAggregator counts payload field values per level during a pass over a file
+type fieldAggregator struct {
+	fields []string
+	all    bool
+	counts map[string]*fieldCounter
+}
+
+// newFieldAggregator creates an aggregator for the given field paths, e.g. "userId" or
+// "request.ip"; "*" aggregates every top level key. It returns nil if no fields are set.
+func newFieldAggregator(fields []string) *fieldAggregator {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	a := &fieldAggregator{counts: make(map[string]*fieldCounter)}
+	for _, field := range fields {
+		if field == allFields {
+			a.all = true
+			continue
+		}
+		a.fields = append(a.fields, field)
+	}
+	return a
+}
+
+// Add counts the configured fields of an entry's payload
+func (a *fieldAggregator) Add(entry *models.LogEntry, level string) {
+	if a == nil || entry == nil || len(entry.Payload) == 0 {
+		return
+	}
+
+	for _, field := range a.fields {
+		if value, ok := lookupField(entry.Payload, field); ok {
+			a.count(field, value, level)
+		}
+	}
+	if a.all {
+		for field, value := range entry.Payload {
+			if _, ok := a.counts[field]; ok || len(a.counts) < maxFields {
+				a.count(field, value, level)
+			}
+		}
+	}
+}
+
+func (a *fieldAggregator) count(field string, value any, level string) {
+	counter, ok := a.counts[field]
+	if !ok {
+		counter = &fieldCounter{values: make(map[string]*models.FieldValue)}
+		a.counts[field] = counter
+	}
+	counter.count++
+
+	key := formatFieldValue(value)
+	fieldValue, ok := counter.values[key]
+	if !ok {
+		if len(counter.values) >= maxFieldValues {
+			counter.truncated = true
+			return
+		}
+		fieldValue = &models.FieldValue{Value: key, Levels: make(map[string]int)}
+		counter.values[key] = fieldValue
+	}
+	fieldValue.Count++
+	if level != "" {
+		fieldValue.Levels[level]++
+	}
+}
+
+// Merge adds the counts of another aggregator with the same fields
+func (a *fieldAggregator) Merge(other *fieldAggregator) {
+	if a == nil || other == nil {
+		return
+	}
+
+	for field, counter := range other.counts {
+		existing, ok := a.counts[field]
+		if !ok {
+			a.counts[field] = counter
+			continue
+		}
+
+		existing.count += counter.count
+		existing.truncated = existing.truncated || counter.truncated
+		for key, value := range counter.values {
+			existingValue, ok := existing.values[key]
+			if !ok {
+				if len(existing.values) >= maxFieldValues {
+					existing.truncated = true
+					continue
+				}
+				existing.values[key] = value
+				continue
+			}
+			existingValue.Count += value.Count
+			for level, count := range value.Levels {
+				existingValue.Levels[level] += count
+			}
+		}
+	}
+}
+
+// fieldSnapshot is the serialisable form of a fieldCounter
+type fieldSnapshot struct {
+	Count     int                 `json:"count"`
+	Truncated bool                `json:"truncated,omitempty"`
+	Values    []models.FieldValue `json:"values"`
+}
+
+// snapshot returns the counts of the aggregator
+func (a *fieldAggregator) snapshot() map[string]fieldSnapshot {
+	if a == nil || len(a.counts) == 0 {
+		return nil
+	}
+
+	snapshot := make(map[string]fieldSnapshot, len(a.counts))
+	for field, counter := range a.counts {
+		values := make([]models.FieldValue, 0, len(counter.values))
+		for _, value := range counter.values {
+			values = append(values, *value)
+		}
+		snapshot[field] = fieldSnapshot{Count: counter.count, Truncated: counter.truncated, Values: values}
+	}
+	return snapshot
+}
+
+// restore replaces the counts of the aggregator with those of a snapshot
+func (a *fieldAggregator) restore(snapshot map[string]fieldSnapshot) {
+	if a == nil {
+		return
+	}
+
+	for field, saved := range snapshot {
+		counter := &fieldCounter{
+			count:     saved.Count,
+			truncated: saved.Truncated,
+			values:    make(map[string]*models.FieldValue, len(saved.Values)),
+		}
+		for _, value := range saved.Values {
+			if value.Levels == nil {
+				value.Levels = make(map[string]int)
+			}
+			counter.values[value.Value] = &value
+		}
+		a.counts[field] = counter
+	}
+}
+
+// Stats returns the top K values and error cross-tab of every field
+func (a *fieldAggregator) Stats(topK int) []models.FieldStats {
+	if a == nil || len(a.counts) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(a.counts))
+	for name := range a.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]models.FieldStats, 0, len(names))
+	for _, name := range names {
+		counter := a.counts[name]
+		values := make([]models.FieldValue, 0, len(counter.values))
+		for _, value := range counter.values {
+			values = append(values, *value)
+		}
+
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		top := append([]models.FieldValue(nil), values[:min(topK, len(values))]...)
+
+		sort.SliceStable(values, func(i, j int) bool { return errorCount(values[i]) > errorCount(values[j]) })
+		var topErrors []models.FieldValue
+		for _, value := range values[:min(topK, len(values))] {
+			if errorCount(value) > 0 {
+				topErrors = append(topErrors, value)
+			}
+		}
+
+		stats = append(stats, models.FieldStats{
+			Field:     name,
+			Count:     counter.count,
+			Distinct:  len(counter.values),
+			Truncated: counter.truncated,
+			Top:       top,
+			TopErrors: topErrors,
+		})
+	}
+	return stats
+}
+
+func errorCount(value models.FieldValue) int {
+	return value.Levels[LevelError] + value.Levels[LevelFatal]
+}
+
+// lookupField resolves a dotted path such as "request.ip" in a payload
+func lookupField(payload map[string]any, path string) (any, bool) {
+	var current any = payload
+	for more := true; more; {
+		var part string
+		part, path, more = strings.Cut(path, ".")
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func formatFieldValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,128 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// errInvalidFilter is a filter of a job that cannot be applied
+var errInvalidFilter = errors.New("invalid filter")
+
+// entryFilter excludes the entries of a job failing its filters before they
+// are counted
+type entryFilter struct {
+	from, to time.Time
+	levels   map[string]bool
+	message  *regexp.Regexp
+	fields   map[string]string
+}
+
+// newEntryFilter compiles the filters of a job, returning nil if there are
+// none. Levels are given by name in any case, field values are compared in
+// their string form so 200 matches both a number and "200".
+func newEntryFilter(filters models.Filters) (*entryFilter, error) {
+	f := &entryFilter{}
+	empty := true
+	if filters.From != nil {
+		f.from, empty = *filters.From, false
+	}
+	if filters.To != nil {
+		f.to, empty = *filters.To, false
+	}
+	if !f.from.IsZero() && !f.to.IsZero() && !f.from.Before(f.to) {
+		return nil, fmt.Errorf("%w: from must be before to", errInvalidFilter)
+	}
+
+	if len(filters.Levels) > 0 {
+		f.levels, empty = make(map[string]bool, len(filters.Levels)), false
+		for _, name := range filters.Levels {
+			level := normalizeLevel(name)
+			if level == "" {
+				return nil, fmt.Errorf("%w: unknown level %q", errInvalidFilter, name)
+			}
+			f.levels[level] = true
+		}
+	}
+
+	if filters.Message != "" {
+		message, err := regexp.Compile(filters.Message)
+		if err != nil {
+			return nil, fmt.Errorf("%w: message: %v", errInvalidFilter, err)
+		}
+		f.message, empty = message, false
+	}
+
+	if len(filters.Fields) > 0 {
+		f.fields, empty = make(map[string]string, len(filters.Fields)), false
+		for path, value := range filters.Fields {
+			// Numbers decoded from the request would otherwise be formatted
+			// with an exponent
+			if number, ok := value.(float64); ok {
+				value = json.Number(strconv.FormatFloat(number, 'f', -1, 64))
+			}
+			f.fields[path] = formatFieldValue(value)
+		}
+	}
+
+	if empty {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// readsEntries reports whether the filter reads the message or payload of
+// entries
+func (f *entryFilter) readsEntries() bool {
+	return f != nil && (f.message != nil || len(f.fields) > 0)
+}
+
+// exclude returns the first filter an entry fails, or an empty string if it
+// passes all of them. Entries without a timestamp fail a time range, entries
+// without a level a level set and entries without a payload field filters;
+// the message of entries that were not parsed is their line.
+func (f *entryFilter) exclude(entry *models.LogEntry, level string, line []byte) string {
+	if f == nil {
+		return ""
+	}
+
+	if !f.from.IsZero() || !f.to.IsZero() {
+		if entry == nil || entry.Timestamp.IsZero() ||
+			(!f.from.IsZero() && entry.Timestamp.Before(f.from)) ||
+			(!f.to.IsZero() && !entry.Timestamp.Before(f.to)) {
+			return models.FilterTime
+		}
+	}
+
+	if f.levels != nil && !f.levels[level] {
+		return models.FilterLevel
+	}
+
+	if f.message != nil {
+		var matched bool
+		if entry != nil && entry.Message != "" {
+			matched = f.message.MatchString(entry.Message)
+		} else {
+			matched = f.message.Match(line)
+		}
+		if !matched {
+			return models.FilterMessage
+		}
+	}
+
+	for path, want := range f.fields {
+		if entry == nil {
+			return models.FilterFields
+		}
+		value, ok := lookupJSONField(entry.Payload, path)
+		if !ok || formatFieldValue(value) != want {
+			return models.FilterFields
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// followPollInterval is how often a followed file is checked for new data
+const followPollInterval = time.Second
+
+// FollowCallback receives the results of a followed file: delta covers the
+// lines appended since the previous call, cumulative every line read since
+// following started. The results are only valid during the call.
+type FollowCallback func(delta, cumulative models.Result)
+
+// follower reads the lines appended to a file that is still being written
+type follower struct {
+	p      *Processor
+	path   string
+	file   *os.File
+	info   os.FileInfo
+	offset int64
+	format string
+	cb     FollowCallback
+
+	delta      *scanState
+	cumulative *scanState
+}
+
+// Follow reads a file that is still being written, like tail -f, passing its
+// results to cb every follow interval while new lines come in, and once more
+// with status completed when ctx is done. Lines are read once complete,
+// starting at the beginning of the file or, if fromEnd is set, at its end,
+// in the format told by its extension or else by the lines it starts with.
+// A file truncated in place is read again from its start, and when the file
+// is rotated the rest of the old file is read before following the new one.
+func (p *Processor) Follow(ctx context.Context, filePath string, fromEnd bool, cb FollowCallback) error {
+	f := &follower{p: p, path: filePath, format: formatOf(filePath), cb: cb}
+	if err := f.open(); err != nil {
+		return err
+	}
+	defer func() { f.file.Close() }()
+
+	compression, err := detectCompression(f.file)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	if compression != "" {
+		return fmt.Errorf("cannot follow %s compressed file %s", compression, filePath)
+	}
+	// Files whose extension names no format are read in the format their
+	// head parses best in, as by ProcessFiles
+	if f.format == FormatText {
+		detection, err := p.detectStream(f.file, compression, f.format)
+		if err != nil {
+			return fmt.Errorf("error reading file %s: %w", filePath, err)
+		}
+		f.format = detection.Format
+	}
+	// Lines are read as they are completed, so values must not span lines
+	if f.format == FormatCSV || (f.format == FormatJSON && f.info.Size() > 0 && !jsonLines(f.file)) {
+		return fmt.Errorf("cannot follow %s file %s, only text logs and JSON Lines can be followed", f.format, filePath)
+	}
+	f.delta = p.newFormatState(f.format, models.ColumnMapping{}, nil)
+	f.cumulative = p.newFormatState(f.format, models.ColumnMapping{}, nil)
+
+	if fromEnd {
+		if f.offset, err = lastLineEnd(f.file, 0, f.info.Size()); err != nil {
+			return fmt.Errorf("error reading file %s: %w", filePath, err)
+		}
+	}
+
+	fmt.Printf("Following file: %s\n", filePath)
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+	published := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			err := f.poll()
+			f.publish(models.StatusCompleted)
+			fmt.Printf("Stopped following file: %s\n", filePath)
+			return err
+		case <-ticker.C:
+			if err := f.poll(); err != nil {
+				f.publish(models.StatusCompleted)
+				return err
+			}
+			if f.delta.lines > 0 && time.Since(published) >= f.p.followInterval {
+				f.publish(models.StatusFollowing)
+				published = time.Now()
+			}
+		}
+	}
+}
+
+// open opens the file at the path of the follower, starting at its beginning
+func (f *follower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("error opening file %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error getting file info: %w", err)
+	}
+	f.file, f.info, f.offset = file, info, 0
+	return nil
+}
+
+// poll reads the lines appended since the last poll, handling rotation and
+// truncation of the file
+func (f *follower) poll() error {
+	// A different file at the path means the followed one was rotated. While
+	// the path is missing the old file is still read.
+	if info, err := os.Stat(f.path); err == nil && !os.SameFile(info, f.info) {
+		if err := f.read(true); err != nil {
+			return err
+		}
+		f.file.Close()
+		f.startOver()
+		if err := f.open(); err != nil {
+			return err
+		}
+		fmt.Printf("Followed file %s was rotated\n", f.path)
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("error getting file info: %w", err)
+	}
+	if info.Size() < f.offset {
+		fmt.Printf("Followed file %s was truncated\n", f.path)
+		f.startOver()
+		f.offset = 0
+	}
+	return f.read(false)
+}
+
+// read scans the lines written past the offset. Unless all is set, a last
+// line without newline is left for later, as it may still be being written.
+func (f *follower) read(all bool) error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return fmt.Errorf("error getting file info: %w", err)
+	}
+
+	end := info.Size()
+	if !all {
+		if end, err = lastLineEnd(f.file, f.offset, end); err != nil {
+			return fmt.Errorf("error reading file %s: %w", f.path, err)
+		}
+	}
+	if end <= f.offset {
+		return nil
+	}
+
+	// Samples of the delta are located from where it started
+	f.delta.offset = f.offset - f.delta.result.TotalBytes
+	state, err := f.p.scanLines(context.Background(), io.NewSectionReader(f.file, f.offset, end-f.offset), f.delta, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error reading file %s: %w", f.path, err)
+	}
+	f.delta = state
+	f.offset = end
+	return nil
+}
+
+// startOver prepares to read a file from its start after the followed one was
+// truncated or rotated. The lines of the delta are published first, as they
+// are located by their offsets in the old file.
+func (f *follower) startOver() {
+	if f.delta.result.TotalBytes > 0 {
+		f.publish(models.StatusFollowing)
+	}
+	f.delta.atStart = true
+}
+
+// publish passes the lines read since the last call and the running total to
+// the callback, then starts a new delta
+func (f *follower) publish(status string) {
+	delta := f.delta.finish(f.path, f.p.topN, f.p.topK)
+	delta.Follow = models.FollowDelta
+	delta.Status = status
+
+	f.cumulative.merge(f.delta)
+	cumulative := f.cumulative.finish(f.path, f.p.topN, f.p.topK)
+	cumulative.Follow = models.FollowCumulative
+	cumulative.Status = status
+
+	f.cb(delta, cumulative)
+	f.delta = f.p.newFormatState(f.format, models.ColumnMapping{}, nil)
+	f.delta.atStart = false
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// TestFollowStartsOver checks that the lines read before a followed file is
+// truncated or rotated are published before the new file is read, and that
+// the samples of each delta are located in the file they were read from
+func TestFollowStartsOver(t *testing.T) {
+	old := "2025-04-01T10:00:00Z INFO started\n2025-04-01T10:00:01Z ERROR failed\n"
+	replaced := "2025-04-01T11:00:00Z WARN restarted\n"
+	tests := []struct {
+		name    string
+		replace func(path string) error
+	}{
+		{"truncated", func(path string) error {
+			return os.WriteFile(path, []byte(replaced), 0o644)
+		}},
+		{"rotated", func(path string) error {
+			if err := os.Rename(path, path+".1"); err != nil {
+				return err
+			}
+			return os.WriteFile(path, []byte(replaced), 0o644)
+		}},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var deltas []models.Result
+			f := &follower{p: p, path: path, format: FormatText, cb: func(delta, _ models.Result) {
+				deltas = append(deltas, delta)
+			}}
+			if err := f.open(); err != nil {
+				t.Fatal(err)
+			}
+			defer func() { f.file.Close() }()
+			f.delta = p.newFormatState(f.format, models.ColumnMapping{}, nil)
+			f.cumulative = p.newFormatState(f.format, models.ColumnMapping{}, nil)
+
+			if err := f.poll(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.replace(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.poll(); err != nil {
+				t.Fatal(err)
+			}
+			f.publish(models.StatusCompleted)
+
+			if len(deltas) != 2 {
+				t.Fatalf("got %d deltas, want 2", len(deltas))
+			}
+			if deltas[0].TotalLines != 2 || deltas[1].TotalLines != 1 {
+				t.Errorf("got deltas of %d and %d lines, want 2 and 1", deltas[0].TotalLines, deltas[1].TotalLines)
+			}
+			for i, delta := range deltas {
+				for _, sample := range delta.Samples {
+					if sample.Offset < 0 {
+						t.Errorf("delta %d has a sample at offset %d", i, sample.Offset)
+					}
+				}
+			}
+			if samples := deltas[1].Samples; len(samples) != 1 || samples[0].Offset != 0 || samples[0].Level != LevelWarn {
+				t.Errorf("got samples %+v after starting over, want the WARN line at offset 0", samples)
+			}
+		})
+	}
+}
+
+// TestFollowDetectsFormat checks that a followed file whose extension names no
+// format is read in the format its head is detected in
+func TestFollowDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		input  string
+		format string
+		err    bool
+	}{
+		{
+			name:   "generator",
+			file:   "app.log",
+			input:  "[2025-04-01T10:00:00Z] INFO started\n[2025-04-01T10:00:01Z] ERROR failed\n",
+			format: FormatText,
+		},
+		{
+			name:   "syslog",
+			file:   "app.log",
+			input:  "<35>Oct 11 22:14:15 host sshd[42]: Failed password\n<30>Oct 11 22:14:16 host cron[7]: job started\n",
+			format: FormatSyslog,
+		},
+		{
+			name:   "json lines",
+			file:   "events.txt",
+			input:  `{"level":"info","msg":"started"}` + "\n" + `{"level":"error","msg":"failed"}` + "\n",
+			format: FormatJSON,
+		},
+		{
+			name:  "csv",
+			file:  "app.log",
+			input: "timestamp,level,message\n2025-04-01T10:00:00Z,INFO,started\n2025-04-01T10:00:01Z,ERROR,failed\n",
+			err:   true,
+		},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			// A done context reads the file once and publishes the result
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			var result models.Result
+			err := p.Follow(ctx, path, false, func(_, cumulative models.Result) { result = cumulative })
+			if tt.err {
+				if err == nil {
+					t.Fatal("followed a file that cannot be followed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Format != tt.format || result.TotalLines != 2 || result.MalformedLines != 0 || result.Levels[LevelError] != 1 {
+				t.Errorf("got format %q, %d lines, %d malformed and levels %v, want %q, 2 lines with one ERROR",
+					result.Format, result.TotalLines, result.MalformedLines, result.Levels, tt.format)
+			}
+		})
+	}
+}
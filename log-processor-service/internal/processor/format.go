@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// errUnknownFormat is a format requested for a job that no parser reads
+var errUnknownFormat = errors.New("unknown log format")
+
+// Formats of log files
+const (
+	// FormatText is the "[RFC3339] LEVEL message {json}" format written by
+	// the log generator, which files without a known extension are read as
+	// unless their head parses better in another format
+	FormatText = "text"
+	// FormatCSV is a CSV file with a header naming its columns
+	FormatCSV = "csv"
+	// FormatJSON is a file of JSON Lines or a JSON array of entries
+	FormatJSON = "json"
+	// FormatAccess is the combined or common access log of Nginx and Apache
+	FormatAccess = "access"
+	// FormatSyslog is syslog in the RFC 3164 or RFC 5424 format
+	FormatSyslog = "syslog"
+	// FormatLogfmt is lines of key=value pairs
+	FormatLogfmt = "logfmt"
+)
+
+// LineParser parses the lines of a text log format into entries
+type LineParser interface {
+	// Name returns the format the parser reads
+	Name() string
+	// Parse returns the entry of a line. The level is empty if the line has
+	// none, in which case the classifier decides, and the timestamp is zero
+	// if it has none.
+	Parse(line []byte) (models.LogEntry, error)
+	// EntryStart returns the pattern of the lines starting an entry, which
+	// other lines such as the frames of a stack trace continue, or nil for
+	// the configured pattern
+	EntryStart() *regexp.Regexp
+}
+
+// lineParsers are the parsers of the text formats by name, and parserNames
+// their names in the order they were registered, most specific first
+var (
+	lineParsers = map[string]LineParser{}
+	parserNames []string
+)
+
+func registerParser(parser LineParser) {
+	lineParsers[parser.Name()] = parser
+	parserNames = append(parserNames, parser.Name())
+}
+
+func init() {
+	registerParser(accessLogParser{})
+	registerParser(syslogParser{})
+	registerParser(textParser{})
+	registerParser(logfmtParser{})
+}
+
+// textParser parses the lines of the generator's format
+type textParser struct{}
+
+func (textParser) Name() string { return FormatText }
+
+func (textParser) Parse(line []byte) (models.LogEntry, error) { return ParseLogEntry(line) }
+
+func (textParser) EntryStart() *regexp.Regexp { return nil }
+
+// knownFormat reports whether files can be read in a format
+func knownFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatJSON:
+		return true
+	}
+	_, ok := lineParsers[format]
+	return ok
+}
+
+// formatExtensions maps file extensions to the format of the file
+var formatExtensions = map[string]string{
+	".csv":    FormatCSV,
+	".json":   FormatJSON,
+	".jsonl":  FormatJSON,
+	".ndjson": FormatJSON,
+}
+
+// formatOf returns the format of a file going by its name without
+// compression extensions
+func formatOf(name string) string {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".gz", ".zst", ".bz2"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if format, ok := formatExtensions[filepath.Ext(name)]; ok {
+		return format
+	}
+	return FormatText
+}
+
+// newFormatState returns a fresh state for a file of the given format, whose
+// entries pass the filter
+func (p *Processor) newFormatState(format string, columns models.ColumnMapping, filter *entryFilter) *scanState {
+	state := p.newScanState()
+	state.format = format
+	state.filter = filter
+	switch format {
+	case FormatCSV:
+		state.csv = newCSVLayout(columns)
+	case FormatJSON:
+		state.json = &p.jsonFields
+	case FormatText:
+		// Lines of the generator's format take the fast path of observe
+	default:
+		if parser, ok := lineParsers[format]; ok {
+			state.parser = parser
+			state.entryStart = p.entryStartOf(format)
+		}
+	}
+	if format == FormatAccess {
+		state.fields = newFieldAggregator(withFields(p.fields, accessLogFields...))
+	}
+	return state
+}
+
+// entryStartOf returns the pattern of the lines starting an entry of a line
+// format, the configured one unless the parser of the format has its own. Every
+// line is an entry of its own if no pattern is configured.
+func (p *Processor) entryStartOf(format string) *regexp.Regexp {
+	if p.entryStart == nil {
+		return nil
+	}
+	if parser, ok := lineParsers[format]; ok {
+		if start := parser.EntryStart(); start != nil {
+			return start
+		}
+	}
+	return p.entryStart
+}
+
+// withFields returns the fields with the extra ones not already among them
+func withFields(fields []string, extra ...string) []string {
+	combined := append([]string(nil), fields...)
+	for _, field := range extra {
+		if !slices.Contains(combined, field) {
+			combined = append(combined, field)
+		}
+	}
+	return combined
+}
+
+// splittable reports whether a file of the given format can be split into
+// newline aligned chunks. CSV records and the values of a JSON array may span
+// lines, while JSON Lines have one value per line.
+func splittable(format string, r io.ReaderAt) bool {
+	switch format {
+	case FormatCSV:
+		return false
+	case FormatJSON:
+		return jsonLines(r)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,272 @@
+package processor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	// indexDir is the directory of the upload directory holding the indexes
+	// of the uploaded files, each named after its file with indexExtension
+	indexDir       = ".index"
+	indexExtension = ".idx"
+
+	// minTermLength and maxTermLength bound the terms indexed
+	minTermLength = 2
+	maxTermLength = 64
+
+	// indexBlockTerms is the number of terms in a block of the dictionary,
+	// the unit it is searched in
+	indexBlockTerms = 64
+)
+
+// indexMagic starts and ends index files, its last byte is the version
+var indexMagic = []byte("LPIDX\x00\n\x01")
+
+// indexUnit is a line, or a CSV record or JSON value, of an indexed file
+type indexUnit struct {
+	offset int64
+	line   int
+}
+
+// termPostings are the units holding a term, as uvarint deltas
+type termPostings struct {
+	data  []byte
+	last  int
+	count int
+}
+
+func (t *termPostings) add(unit int) {
+	if t.count > 0 && unit == t.last {
+		return
+	}
+	t.data = binary.AppendUvarint(t.data, uint64(unit-t.last))
+	t.last = unit
+	t.count++
+}
+
+// indexBuilder builds the inverted index of a file, mapping the lower-cased
+// terms of its lines to the lines they appear in. Indexes of consecutive
+// ranges of a file can be merged like the rest of a scanState; they are not
+// part of checkpoints, the lines of a resumed chunk scanned before its
+// checkpoint are indexed again instead.
+type indexBuilder struct {
+	units []indexUnit
+	terms map[string]*termPostings
+	lower []byte
+}
+
+func newIndexBuilder() *indexBuilder {
+	return &indexBuilder{terms: make(map[string]*termPostings)}
+}
+
+// Add indexes the terms of a line, numbered within the range of the scan and
+// starting at offset in the file
+func (b *indexBuilder) Add(line int, offset int64, text []byte) {
+	if b == nil {
+		return
+	}
+	unit := len(b.units)
+	b.units = append(b.units, indexUnit{offset: offset, line: line})
+
+	b.lower = appendLower(b.lower[:0], text)
+	forEachTerm(b.lower, func(term []byte) {
+		postings, ok := b.terms[string(term)]
+		if !ok {
+			postings = &termPostings{}
+			b.terms[string(term)] = postings
+		}
+		postings.add(unit)
+	})
+}
+
+// Merge appends the index of the range directly following this one, which
+// starts after lines lines. The index of a file is incomplete, and nil, if
+// either range has none.
+func (b *indexBuilder) Merge(next *indexBuilder, lines int) *indexBuilder {
+	if b == nil || next == nil {
+		return nil
+	}
+	base := len(b.units)
+	for _, unit := range next.units {
+		b.units = append(b.units, indexUnit{offset: unit.offset, line: unit.line + lines})
+	}
+	for term, other := range next.terms {
+		postings, ok := b.terms[term]
+		if !ok {
+			postings = &termPostings{}
+			b.terms[term] = postings
+		}
+		// Only the first delta of the next range is relative to its start
+		first, n := binary.Uvarint(other.data)
+		unit := base + int(first)
+		postings.data = binary.AppendUvarint(postings.data, uint64(unit-postings.last))
+		postings.data = append(postings.data, other.data[n:]...)
+		postings.last = base + other.last
+		postings.count += other.count
+	}
+	return b
+}
+
+// indexPath returns the path of the index of an uploaded file
+func indexPath(uploadDir, filePath string) string {
+	return filepath.Join(uploadDir, indexDir, filepath.Base(filePath)+indexExtension)
+}
+
+// Write saves the index of a file of the given size to path, replacing any
+// earlier one. The file holds, in order:
+//
+//   - the magic
+//   - the offset and line number of every unit, as little-endian uint64
+//     and uint32
+//   - the postings of every term, as uvarint deltas of unit numbers
+//   - the dictionary, blocks of up to indexBlockTerms sorted terms, each
+//     entry its uvarint length and bytes followed by the uvarint offset,
+//     length and count of its postings
+//   - the block table, the first term of every block and the offset of
+//     the block, encoded the same way
+//   - the trailer, the size of the file indexed, the number of units, the
+//     offsets of the postings, dictionary and block table and the number
+//     of blocks as little-endian uint64, then the magic again
+func (b *indexBuilder) Write(path string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	w := &countingWriter{w: bufio.NewWriter(file)}
+	w.write(indexMagic)
+	var unit [12]byte
+	for _, u := range b.units {
+		binary.LittleEndian.PutUint64(unit[:8], uint64(u.offset))
+		binary.LittleEndian.PutUint32(unit[8:], uint32(u.line))
+		w.write(unit[:])
+	}
+
+	terms := make([]string, 0, len(b.terms))
+	for term := range b.terms {
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+
+	postingsStart := w.n
+	postingsAt := make([]int64, len(terms))
+	for i, term := range terms {
+		postingsAt[i] = w.n - postingsStart
+		w.write(b.terms[term].data)
+	}
+
+	dictStart := w.n
+	var blocks []byte
+	var entry []byte
+	for i, term := range terms {
+		if i%indexBlockTerms == 0 {
+			blocks = appendIndexString(blocks, term)
+			blocks = binary.AppendUvarint(blocks, uint64(w.n-dictStart))
+		}
+		postings := b.terms[term]
+		entry = appendIndexString(entry[:0], term)
+		entry = binary.AppendUvarint(entry, uint64(postingsAt[i]))
+		entry = binary.AppendUvarint(entry, uint64(len(postings.data)))
+		entry = binary.AppendUvarint(entry, uint64(postings.count))
+		w.write(entry)
+	}
+
+	blocksStart := w.n
+	w.write(blocks)
+
+	var trailer [48]byte
+	for i, value := range []int64{size, int64(len(b.units)), postingsStart, dictStart, blocksStart, int64((len(terms) + indexBlockTerms - 1) / indexBlockTerms)} {
+		binary.LittleEndian.PutUint64(trailer[i*8:], uint64(value))
+	}
+	w.write(trailer[:])
+	w.write(indexMagic)
+
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// writeIndex saves the index of a file next to it, logging failures, which
+// do not fail the processing of the file
+func (p *Processor) writeIndex(job *fileJob, index *indexBuilder) error {
+	path := indexPath(p.uploadDir, job.filePath)
+	if err := index.Write(path, job.size); err != nil {
+		fmt.Printf("Error writing index of %s: %v\n", job.filePath, err)
+		return err
+	}
+	fmt.Printf("Indexed %d lines and %d terms of %s\n", len(index.units), len(index.terms), job.filePath)
+	return nil
+}
+
+// countingWriter counts the bytes written and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	w.err = err
+}
+
+func appendIndexString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendLower appends text with ASCII letters lower-cased
+func appendLower(b, text []byte) []byte {
+	for _, c := range text {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// forEachTerm calls fn with the terms of lower-cased text: runs of letters,
+// digits and non-ASCII bytes between minTermLength and maxTermLength long
+func forEachTerm(text []byte, fn func(term []byte)) {
+	start := -1
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && termByte(text[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if n := i - start; n >= minTermLength && n <= maxTermLength {
+				fn(text[start:i])
+			}
+			start = -1
+		}
+	}
+}
+
+func termByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c >= 0x80
+}
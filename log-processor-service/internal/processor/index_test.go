@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// searchFixture is the log the search package of the backend reads the index
+// of, which this package writes
+const searchFixture = "../../../backend-service/search/testdata/app.log"
+
+var update = flag.Bool("update", false, "rewrite the index read by the tests of the backend")
+
+// scanIndex indexes the bytes of data from start to end like a chunk
+func scanIndex(t *testing.T, p *Processor, data []byte, start, end int) *scanState {
+	t.Helper()
+	state := p.newFormatState(FormatText, models.ColumnMapping{}, nil)
+	state.atStart = start == 0
+	state.offset = int64(start)
+	state.index = newIndexBuilder()
+	state, err := p.scanLines(context.Background(), bytes.NewReader(data[start:end]), state, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestMergedIndexMatchesWholeFile(t *testing.T) {
+	data, err := os.ReadFile(searchFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	whole := scanIndex(t, p, data, 0, len(data))
+	split := bytes.IndexByte(data[len(data)/2:], '\n') + len(data)/2 + 1
+	merged := scanIndex(t, p, data, 0, split)
+	merged.merge(scanIndex(t, p, data, split, len(data)))
+	if merged.index == nil {
+		t.Fatal("merged index is nil")
+	}
+	if len(merged.index.terms) <= indexBlockTerms {
+		t.Fatalf("fixture has %d terms, want more than a block of %d", len(merged.index.terms), indexBlockTerms)
+	}
+
+	dir := t.TempDir()
+	wholePath, mergedPath := filepath.Join(dir, "whole.idx"), filepath.Join(dir, "merged.idx")
+	if err := whole.index.Write(wholePath, int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.index.Write(mergedPath, int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(wholePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(mergedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("index merged from two chunks differs from the index of the whole file")
+	}
+
+	if *update {
+		if err := merged.index.Write(searchFixture+indexExtension, int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture, err := os.ReadFile(searchFixture + indexExtension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, fixture) {
+		t.Errorf("index differs from %s%s, run the tests with -update if the format changed", searchFixture, indexExtension)
+	}
+}
@@ -0,0 +1,363 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// jsonStackFields are the fields stack traces of JSON entries are read from
+// besides the message
+var jsonStackFields = []string{"stack_trace", "stack", "error.stack_trace", "error.stack", "exc_info"}
+
+// jsonFields are the paths of the fields the level, timestamp and message of
+// JSON entries are read from, the first present of each is used
+type jsonFields struct {
+	level     []string
+	timestamp []string
+	message   []string
+}
+
+// jsonLines reports whether a JSON file holds JSON Lines rather than an array,
+// going by its first byte other than whitespace
+func jsonLines(r io.ReaderAt) bool {
+	head := make([]byte, 512)
+	n, _ := r.ReadAt(head, 0)
+	for _, b := range bytes.TrimPrefix(head[:n], bom) {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return b == '{'
+		}
+	}
+	return false
+}
+
+var bom = []byte("\ufeff")
+
+// jsonSeparator reports whether a byte separates the values of a stream:
+// whitespace, a comma or the bracket of a top level array. The bytes of a
+// UTF-8 byte order mark are skipped too.
+func jsonSeparator(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', ',', '[', ']', 0xEF, 0xBB, 0xBF:
+		return true
+	}
+	return false
+}
+
+// lookupJSONField returns the value at a path of an object, trying the path as
+// a key of its own first, as in {"log.level": "info"}
+func lookupJSONField(object map[string]any, path string) (any, bool) {
+	if value, ok := object[path]; ok {
+		return value, true
+	}
+	return lookupField(object, path)
+}
+
+// first returns the string form of the first of the paths present in an
+// object, or false if none is
+func (f *jsonFields) first(object map[string]any, paths []string) (string, bool) {
+	for _, path := range paths {
+		if value, ok := lookupJSONField(object, path); ok && value != nil {
+			return formatFieldValue(value), true
+		}
+	}
+	return "", false
+}
+
+// entry reads the level, timestamp and message of an object, which is its
+// payload. The level is empty unless the level field holds a known level.
+func (f *jsonFields) entry(object map[string]any) (models.LogEntry, error) {
+	entry := models.LogEntry{Payload: object}
+	entry.Message, _ = f.first(object, f.message)
+	if value, ok := f.first(object, f.level); ok {
+		entry.Level = normalizeLevel(value)
+	}
+	if timestamp, ok := f.first(object, f.timestamp); ok {
+		t, err := parseTimestampValue(timestamp)
+		if err != nil {
+			return entry, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		entry.Timestamp = t
+	}
+	return entry, nil
+}
+
+// observeValue analyses a value of a JSON stream. Values that are not objects
+// are malformed and classified as text.
+func (s *scanState) observeValue(r *jsonReader) {
+	line := s.lines + r.line + 1
+	s.lines += r.lines
+	result := &s.result
+	result.TotalLines += r.lines
+	if len(r.record) == 0 {
+		return
+	}
+	s.index.Add(line, s.offset+result.TotalBytes+int64(r.start), r.record)
+	result.LongestLine = max(result.LongestLine, r.length)
+	if r.truncated {
+		result.OversizedLines++
+	}
+
+	object, err := decodePayload(r.record)
+	if err != nil {
+		level := s.classifier.Classify(r.record)
+		if excluded := s.filter.exclude(nil, level, r.record); excluded != "" {
+			result.AddExcluded(excluded, r.lines)
+			return
+		}
+		result.Entries++
+		s.countLevel(level)
+		// A value cut off by truncation is not malformed
+		if !r.truncated {
+			s.malformed(line, fmt.Errorf("invalid JSON: %v", err))
+		}
+		if level != "" {
+			s.samples.Add(level, line, s.offset+result.TotalBytes+int64(r.start), r.record)
+			s.templates.Add(level, nil, r.record)
+		}
+		return
+	}
+
+	entry, err := s.json.entry(object)
+	level := entry.Level
+	if level == "" {
+		value, hasLevel := s.json.first(object, s.json.level)
+		switch {
+		case hasLevel:
+			level = s.classifier.Classify([]byte(value))
+		case entry.Message != "":
+			level = s.classifier.Classify([]byte(entry.Message))
+		default:
+			level = s.classifier.Classify(r.record)
+		}
+		entry.Level = level
+	}
+	message := entry.Message
+	if end := strings.IndexByte(entry.Message, '\n'); end >= 0 {
+		entry.Message = entry.Message[:end]
+	}
+
+	var parsed *models.LogEntry
+	if err == nil {
+		parsed = &entry
+	}
+	if excluded := s.filter.exclude(parsed, level, r.record); excluded != "" {
+		result.AddExcluded(excluded, r.lines)
+		return
+	}
+
+	result.Entries++
+	s.countLevel(level)
+	for _, text := range s.jsonStackTraces(object, message) {
+		if trace, ok := parseStackTrace(strings.Split(text, "\n")); ok {
+			s.exceptions.Add(trace, level)
+			break
+		}
+	}
+	if err != nil {
+		s.malformed(line, err)
+	} else if !entry.Timestamp.IsZero() {
+		result.AddTimestamp(entry.Timestamp)
+	}
+	s.fields.Add(parsed, level)
+
+	if level == "" {
+		return
+	}
+	s.samples.Add(level, line, s.offset+result.TotalBytes+int64(r.start), r.record)
+	if parsed != nil && !entry.Timestamp.IsZero() {
+		s.timeline.Add(entry.Timestamp, level)
+	}
+	if entry.Message != "" {
+		s.templates.Add(level, &models.LogEntry{Message: entry.Message}, r.record)
+	} else {
+		s.templates.Add(level, nil, r.record)
+	}
+}
+
+// jsonStackTraces returns the multiline message and stack fields of an entry
+func (s *scanState) jsonStackTraces(object map[string]any, message string) []string {
+	var texts []string
+	if strings.Contains(message, "\n") {
+		texts = append(texts, message)
+	}
+	for _, path := range jsonStackFields {
+		if value, ok := lookupJSONField(object, path); ok {
+			if text, ok := value.(string); ok && strings.Contains(text, "\n") {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return texts
+}
+
+// jsonReader reads the values of a stream of JSON Lines or of a JSON array,
+// whose values may span several lines. Values are truncated to maxRecord
+// bytes; like lineReader it reuses its buffer, so a value is only valid
+// until the next call to Next.
+type jsonReader struct {
+	r         *bufio.Reader
+	maxRecord int
+
+	record    []byte
+	line      int  // newlines read before the start of the value
+	start     int  // bytes read before the start of the value
+	lines     int  // lines read by the last call to Next
+	length    int  // full length of the value
+	truncated bool // whether the value was cut off
+	partial   bool // whether bytes were read since the last newline
+	array     bool // whether the values are inside a top level array
+}
+
+func newJSONReader(r io.Reader, maxRecord int) *jsonReader {
+	return &jsonReader{r: bufio.NewReaderSize(r, readBufferSize), maxRecord: maxRecord}
+}
+
+// Next reads the next value and returns the number of bytes it occupied in
+// the stream, including the separators before it and the rest of its line.
+// The value is empty if only separators were left. It returns io.EOF once the
+// stream is exhausted.
+func (r *jsonReader) Next() (int, error) {
+	r.record = r.record[:0]
+	r.line, r.start, r.lines, r.length, r.truncated = 0, 0, 0, 0, false
+	consumed := 0
+
+	// Skip the separators up to the start of the value
+	var b byte
+	for {
+		c, err := r.readByte()
+		if err == io.EOF && consumed > 0 {
+			return consumed, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		consumed++
+		if !jsonSeparator(c) {
+			b = c
+			break
+		}
+		r.separator(c)
+	}
+	r.line, r.start = r.lines, consumed-1
+
+	// Objects end with their closing brace and strings with their closing
+	// quote, other values at the line end or, in an array, the next comma
+	// or bracket. Quotes and brackets inside other values, such as a line
+	// of plain text, do not delimit them.
+	structured := b == '{' || b == '"'
+	depth, inString, escaped := 0, false, false
+	for {
+		r.add(b)
+		switch {
+		case !structured:
+		case inString && escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		case inString:
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+		}
+		if !inString && depth <= 0 && ((structured && (b == '}' || b == ']' || b == '"')) || r.atSeparator()) {
+			break
+		}
+
+		c, err := r.readByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		consumed++
+		b = c
+	}
+
+	// Take the rest of the line if it holds nothing but separators, so the
+	// next value starts on a line of its own
+	for {
+		next, err := r.r.Peek(1)
+		if err != nil || !jsonSeparator(next[0]) {
+			break
+		}
+		c, _ := r.readByte()
+		consumed++
+		r.separator(c)
+		if c == '\n' {
+			break
+		}
+	}
+	if _, err := r.r.Peek(1); err == io.EOF && r.partial {
+		r.lines++
+		r.partial = false
+	}
+	return consumed, nil
+}
+
+// readByte reads a byte, counting the lines read
+func (r *jsonReader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == io.EOF && r.partial {
+		// The last line of the stream has no newline
+		r.lines++
+		r.partial = false
+	}
+	if err != nil {
+		return 0, err
+	}
+	if b == '\n' {
+		r.lines++
+		r.partial = false
+	} else {
+		r.partial = true
+	}
+	return b, nil
+}
+
+// separator notes the brackets of a top level array among separators
+func (r *jsonReader) separator(c byte) {
+	switch c {
+	case '[':
+		r.array = true
+	case ']':
+		r.array = false
+	}
+}
+
+// atSeparator reports whether the next byte ends a scalar value. Spaces do
+// not, nor do commas outside of an array, so a line of plain text is read as
+// a single malformed value.
+func (r *jsonReader) atSeparator() bool {
+	next, err := r.r.Peek(1)
+	if err != nil {
+		return true
+	}
+	switch next[0] {
+	case '\r', '\n':
+		return true
+	case ',', ']':
+		return r.array
+	}
+	return false
+}
+
+// add appends a byte of the value unless it is over the maximum length
+func (r *jsonReader) add(b byte) {
+	r.length++
+	if len(r.record) < r.maxRecord {
+		r.record = append(r.record, b)
+		return
+	}
+	r.truncated = true
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestJSONReaderValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "json lines",
+			input: "{\"level\":\"info\"}\n{\"level\":\"error\"}\n",
+			want:  []string{`{"level":"info"}`, `{"level":"error"}`},
+		},
+		{
+			name:  "json lines without final newline",
+			input: "{\"a\":1}\r\n{\"b\":2}",
+			want:  []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:  "array",
+			input: "[{\"a\":1}, {\"b\":[1,2]}]\n",
+			want:  []string{`{"a":1}`, `{"b":[1,2]}`},
+		},
+		{
+			name:  "pretty printed array",
+			input: "[\n  {\n    \"a\": 1\n  },\n  {\n    \"b\": 2\n  }\n]\n",
+			want:  []string{"{\n    \"a\": 1\n  }", "{\n    \"b\": 2\n  }"},
+		},
+		{
+			name:  "array of scalars",
+			input: "[1, \"two\", true]",
+			want:  []string{"1", `"two"`, "true"},
+		},
+		{
+			name:  "escaped quotes and braces in strings",
+			input: "{\"msg\":\"a \\\"}\\\" b\"}\n\"text with } and ]\"\n",
+			want:  []string{`{"msg":"a \"}\" b"}`, `"text with } and ]"`},
+		},
+		{
+			name:  "byte order mark",
+			input: "\ufeff{\"a\":1}\n",
+			want:  []string{`{"a":1}`},
+		},
+		{
+			name:  "plain text line with quotes",
+			input: "user \"bob\" logged in\n{\"a\":1}\n",
+			want:  []string{`user "bob" logged in`, `{"a":1}`},
+		},
+		{
+			name:  "plain text line with commas and brackets",
+			input: "disk full, retrying [attempt 2]\n",
+			want:  []string{"disk full, retrying [attempt 2]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newJSONReader(strings.NewReader(tt.input), defaultMaxLineLength)
+			var got []string
+			consumed := 0
+			for {
+				n, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				consumed += n
+				if len(reader.record) > 0 {
+					got = append(got, string(reader.record))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got values %q, want %q", got, tt.want)
+			}
+			if consumed != len(tt.input) {
+				t.Errorf("consumed %d bytes, want %d", consumed, len(tt.input))
+			}
+		})
+	}
+}
+
+func TestJSONEntries(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		entries   int
+		malformed int
+		levels    map[string]int
+	}{
+		{
+			name:    "json lines",
+			input:   "{\"level\":\"info\",\"msg\":\"started\"}\n{\"level\":\"error\",\"msg\":\"failed\"}\n",
+			entries: 2,
+			levels:  map[string]int{LevelInfo: 1, LevelError: 1},
+		},
+		{
+			name:    "array",
+			input:   "[\n  {\"severity\": \"WARNING\", \"message\": \"slow\"},\n  {\"log.level\": \"debug\"}\n]\n",
+			entries: 2,
+			levels:  map[string]int{LevelWarn: 1, LevelDebug: 1},
+		},
+		{
+			name:      "plain text line",
+			input:     "{\"level\":\"info\"}\nuser \"bob\" logged in\n",
+			entries:   2,
+			malformed: 1,
+			levels:    map[string]int{LevelInfo: 1},
+		},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := p.newFormatState(FormatJSON, models.ColumnMapping{}, nil)
+			state, err := p.scanLines(context.Background(), strings.NewReader(tt.input), state, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := state.result
+			if result.Entries != tt.entries || result.MalformedLines != tt.malformed {
+				t.Errorf("got %d entries, %d malformed, want %d, %d", result.Entries, result.MalformedLines, tt.entries, tt.malformed)
+			}
+			if !reflect.DeepEqual(result.Levels, tt.levels) {
+				t.Errorf("got levels %v, want %v", result.Levels, tt.levels)
+			}
+			if want := strings.Count(tt.input, "\n"); result.TotalLines != want {
+				t.Errorf("got %d lines, want %d", result.TotalLines, want)
+			}
+		})
+	}
+}
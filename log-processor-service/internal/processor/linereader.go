@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+const (
+	// readBufferSize is the initial size of the buffer lines are read into
+	readBufferSize = 256 * 1024
+	// defaultMaxLineLength matches the token limit of bufio.Scanner, longer
+	// lines are truncated
+	defaultMaxLineLength = bufio.MaxScanTokenSize
+)
+
+// lineReader splits a stream into lines without allocating: the returned slices
+// point into a reused buffer and are only valid until the next call to Next.
+// Like bufio.ScanLines it strips the trailing "\r\n" or "\n". Lines longer than
+// maxLine are truncated to their first maxLine bytes and the rest is skipped.
+type lineReader struct {
+	r       io.Reader
+	buf     []byte
+	start   int  // start of the unread data
+	end     int  // end of the buffered data
+	checked int  // buffered bytes already searched for a newline
+	dropped int  // bytes of the current line skipped beyond maxLine
+	lastCR  bool // whether the last skipped byte was a '\r'
+	length  int  // full length of the last line returned
+	err     error
+	maxLine int
+}
+
+func newLineReader(r io.Reader, maxLine int) *lineReader {
+	return &lineReader{
+		r:       r,
+		buf:     make([]byte, max(readBufferSize, min(maxLine+1, 4*readBufferSize))),
+		maxLine: maxLine,
+	}
+}
+
+// Next returns the next line and the number of bytes it occupied in the stream,
+// including the line terminator. It returns io.EOF once the stream is exhausted.
+func (lr *lineReader) Next() ([]byte, int, error) {
+	for {
+		if i := bytes.IndexByte(lr.buf[lr.start+lr.checked:lr.end], '\n'); i >= 0 {
+			line, n := lr.take(lr.checked+i, 1)
+			return line, n, nil
+		}
+		lr.checked = lr.end - lr.start
+
+		// Keep only the head of an oversized line and skip the rest as it is read
+		if lr.checked > lr.maxLine {
+			lr.dropped += lr.checked - lr.maxLine
+			lr.lastCR = lr.buf[lr.end-1] == '\r'
+			lr.end = lr.start + lr.maxLine
+			lr.checked = lr.maxLine
+		}
+
+		if lr.err != nil {
+			if lr.start < lr.end || lr.dropped > 0 {
+				line, n := lr.take(lr.end-lr.start, 0)
+				return line, n, nil
+			}
+			return nil, 0, lr.err
+		}
+
+		lr.fill()
+	}
+}
+
+// Length returns the full length of the last line returned by Next, which is
+// longer than the line itself if it was truncated
+func (lr *lineReader) Length() int {
+	return lr.length
+}
+
+// take returns the size buffered bytes of the current line, which are followed
+// by a terminator of skip bytes, and the number of bytes consumed
+func (lr *lineReader) take(size, skip int) ([]byte, int) {
+	length := lr.dropped + size
+	switch {
+	case lr.dropped > 0 && size == lr.maxLine:
+		// Nothing was read after the skipped bytes, which may end in the '\r'
+		if lr.lastCR {
+			length--
+		}
+	case size > 0 && lr.buf[lr.start+size-1] == '\r':
+		size--
+		skip++
+		length--
+	}
+	line := lr.buf[lr.start : lr.start+min(size, lr.maxLine)]
+	consumed := lr.dropped + size + skip
+	lr.length = length
+
+	lr.start += size + skip
+	lr.checked = 0
+	lr.dropped = 0
+	return line, consumed
+}
+
+// fill reads more data, moving unread data to the front or growing the buffer if needed
+func (lr *lineReader) fill() {
+	if lr.start > 0 && lr.end == len(lr.buf) {
+		copy(lr.buf, lr.buf[lr.start:lr.end])
+		lr.end -= lr.start
+		lr.start = 0
+	}
+	if lr.end == len(lr.buf) {
+		buf := make([]byte, 2*len(lr.buf))
+		copy(buf, lr.buf[lr.start:lr.end])
+		lr.end -= lr.start
+		lr.start = 0
+		lr.buf = buf
+	}
+
+	n, err := lr.r.Read(lr.buf[lr.end:])
+	lr.end += n
+	if err != nil {
+		lr.err = err
+	}
+}
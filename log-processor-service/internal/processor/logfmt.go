@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// Keys the level, timestamp and message of logfmt lines are read from
+var (
+	logfmtLevelKeys     = []string{"level", "lvl", "severity"}
+	logfmtTimestampKeys = []string{"time", "ts", "timestamp", "t"}
+	logfmtMessageKeys   = []string{"msg", "message"}
+)
+
+var errInvalidLogfmt = errors.New("not a logfmt line")
+
+// logfmtEntryStart matches the key of the first pair starting a logfmt line
+var logfmtEntryStart = regexp.MustCompile(`^[^\s="]+=`)
+
+// logfmtParser parses lines of key=value pairs, as written by Heroku, Go kit
+// and logrus, whose values may be quoted:
+//
+//	time=2025-01-02T15:04:05Z level=info msg="request served" path=/api status=200
+//
+// A key without value is true. The keys other than level, timestamp and
+// message make up the payload.
+type logfmtParser struct{}
+
+func (logfmtParser) Name() string { return FormatLogfmt }
+
+func (logfmtParser) EntryStart() *regexp.Regexp { return logfmtEntryStart }
+
+func (logfmtParser) Parse(line []byte) (models.LogEntry, error) {
+	var entry models.LogEntry
+	pairs, err := parseLogfmt(bytes.TrimRight(line, "\r"))
+	if err != nil {
+		return entry, err
+	}
+
+	used := make(map[string]bool)
+	first := func(keys []string) (string, bool) {
+		for _, key := range keys {
+			if value, ok := pairs[key]; ok {
+				used[key] = true
+				if s, ok := value.(string); ok {
+					return s, true
+				}
+				return "", false
+			}
+		}
+		return "", false
+	}
+
+	if value, ok := first(logfmtLevelKeys); ok {
+		entry.Level = normalizeLevel(value)
+	}
+	entry.Message, _ = first(logfmtMessageKeys)
+	if value, ok := first(logfmtTimestampKeys); ok {
+		if entry.Timestamp, err = parseTimestampValue(value); err != nil {
+			err = fmt.Errorf("invalid timestamp: %v", err)
+		}
+	}
+
+	for key, value := range pairs {
+		if !used[key] {
+			if entry.Payload == nil {
+				entry.Payload = make(map[string]any, len(pairs))
+			}
+			entry.Payload[key] = value
+		}
+	}
+	return entry, err
+}
+
+// parseLogfmt splits a line into its key=value pairs. Lines without any
+// value, such as plain text, are not logfmt.
+func parseLogfmt(line []byte) (map[string]any, error) {
+	pairs := make(map[string]any)
+	values := 0
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("%w: unexpected %q at column %d", errInvalidLogfmt, line[i], i+1)
+		}
+		key := string(line[start:i])
+		if i >= len(line) || line[i] != '=' {
+			pairs[key] = true
+			continue
+		}
+		i++
+		values++
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("%w: unterminated value of %s", errInvalidLogfmt, key)
+			}
+			value, err := strconv.Unquote(string(line[i : end+1]))
+			if err != nil {
+				value = string(line[i+1 : end])
+			}
+			pairs[key] = value
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		pairs[key] = string(line[start:i])
+	}
+
+	if values == 0 {
+		return nil, fmt.Errorf("%w: no key=value pairs", errInvalidLogfmt)
+	}
+	return pairs, nil
+}
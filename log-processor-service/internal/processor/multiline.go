@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+)
+
+// errUnparsedContinuation is a line continuing an entry that is not part of a
+// stack trace
+var errUnparsedContinuation = errors.New("continuation line is not part of a stack trace")
+
+// maxEntryLines bounds the continuation lines kept of an entry for stack trace
+// parsing. The last line is kept on top, as Python tracebacks end with the
+// exception.
+const maxEntryLines = 64
+
+// pendingEntry is a log entry whose continuation lines may still follow
+type pendingEntry struct {
+	start []byte // first line, empty for continuation lines without an entry
+	level string
+	lines []string
+	last  string // last continuation line, once lines is full
+	count int    // number of continuation lines
+
+	// unparsed counts the continuation lines that are not blank, which are
+	// malformed unless they make up a stack trace, and first is the line
+	// number of the first of them within the scan
+	unparsed int
+	first    int
+
+	// excluded is the filter that excluded the entry, whose continuation
+	// lines are excluded with it
+	excluded string
+}
+
+// reset starts a new entry, reusing the buffers of the previous one
+func (e *pendingEntry) reset(start []byte, level string) {
+	e.start = append(e.start[:0], start...)
+	e.level = level
+	e.lines = e.lines[:0]
+	e.last = ""
+	e.count = 0
+	e.unparsed, e.first = 0, 0
+	e.excluded = ""
+}
+
+// add appends a continuation line
+func (e *pendingEntry) add(line string) {
+	e.count++
+	if len(e.lines) < maxEntryLines {
+		e.lines = append(e.lines, line)
+		return
+	}
+	e.last = line
+}
+
+// addUnparsed counts continuation lines that are not blank, starting at the
+// given line
+func (e *pendingEntry) addUnparsed(line, count int) {
+	if e.unparsed == 0 {
+		e.first = line
+	}
+	e.unparsed += count
+}
+
+// extend appends the continuation lines of another entry without a start,
+// whose lines are numbered from base
+func (e *pendingEntry) extend(other *pendingEntry, base int) {
+	for _, line := range other.lines {
+		e.add(line)
+	}
+	if skipped := other.count - len(other.lines); skipped > 0 {
+		e.count += skipped - 1
+		e.add(other.last)
+	}
+	if other.unparsed > 0 {
+		e.addUnparsed(base+other.first, other.unparsed)
+	}
+}
+
+// trace parses the stack trace of an entry with continuation lines
+func (e *pendingEntry) trace() (stackTrace, bool) {
+	if e == nil || e.count == 0 {
+		return stackTrace{}, false
+	}
+
+	lines := make([]string, 0, len(e.lines)+2)
+	if len(e.start) > 0 {
+		lines = append(lines, string(e.start))
+	}
+	lines = append(lines, e.lines...)
+	if e.count > len(e.lines) {
+		lines = append(lines, e.last)
+	}
+	return parseStackTrace(lines)
+}
+
+// startsEntry reports whether a line starts a new entry. The first line of a
+// file always does, while continuation lines at the start of a later chunk
+// belong to the last entry of the chunk before. Blank lines never do, as they
+// may separate the parts of a stack trace.
+func (s *scanState) startsEntry(line []byte) bool {
+	if s.entry == nil && s.atStart {
+		return true
+	}
+	return len(bytes.TrimSpace(line)) > 0 && s.entryStart.Match(line)
+}
+
+// continueEntry adds a continuation line to the current entry
+func (s *scanState) continueEntry(line []byte) {
+	if s.entry != nil && s.entry.excluded != "" {
+		s.result.AddExcluded(s.entry.excluded, 1)
+		return
+	}
+	s.result.ContinuationLines++
+	entry := s.entry
+	if entry == nil {
+		entry = &s.head
+	}
+	entry.add(string(line))
+	if len(bytes.TrimSpace(line)) > 0 {
+		entry.addUnparsed(s.lines, 1)
+	}
+}
+
+// finishEntry counts the stack trace of the current entry, which is complete,
+// or its continuation lines as malformed if they hold none
+func (s *scanState) finishEntry() {
+	if trace, ok := s.entry.trace(); ok {
+		s.exceptions.Add(trace, s.entry.level)
+	} else if s.entry != nil && s.entry.unparsed > 0 {
+		s.malformed(s.entry.first, errUnparsedContinuation)
+		s.result.MalformedLines += s.entry.unparsed - 1
+	}
+}
+
+// mergeEntries joins the entries of the range directly following this one,
+// before its lines are added: its leading continuation lines belong to the
+// current entry, and are excluded with it
+func (s *scanState) mergeEntries(next *scanState) {
+	if next.head.count > 0 {
+		switch {
+		case s.entry != nil && s.entry.excluded != "":
+			s.result.ContinuationLines -= next.head.count
+			s.result.AddExcluded(s.entry.excluded, next.head.count)
+		case s.entry != nil:
+			s.entry.extend(&next.head, s.lines)
+		default:
+			s.head.extend(&next.head, s.lines)
+		}
+	}
+	if next.entry != nil {
+		s.finishEntry()
+		s.entry = next.entry
+		s.entry.first += s.lines
+	}
+	s.exceptions.Merge(next.exceptions)
+}
@@ -0,0 +1,160 @@
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime/debug"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// FileOutcome is the outcome of one requested file. A processed file has one
+// result, or one per member plus the total for archives. A failed file has no
+// results and Err tells why; a cancelled file may have partial results too.
+type FileOutcome struct {
+	FileName string
+	Results  []models.Result
+	Err      error
+	Category models.ErrorCategory
+
+	// RestoredLines and RestoredBytes are the lines and bytes of the results
+	// restored from checkpoints rather than scanned by this run
+	RestoredLines int
+	RestoredBytes int64
+}
+
+// Failed reports whether the file produced no results
+func (o FileOutcome) Failed() bool {
+	return len(o.Results) == 0
+}
+
+// newFailedOutcome creates the outcome of a file that could not be processed
+func newFailedOutcome(fileName string, err error) FileOutcome {
+	return FileOutcome{FileName: fileName, Err: err, Category: Categorize(err)}
+}
+
+// panicError is a panic recovered while processing a file
+type panicError struct {
+	value any
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recovered turns a panic of fn into an error, so a single file cannot bring
+// down the processor
+func recovered(fn func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			fmt.Printf("Recovered from panic: %v\n%s\n", value, debug.Stack())
+			err = &panicError{value: value}
+		}
+	}()
+	return fn()
+}
+
+// decodeError is a failure to decompress or unpack a file, as opposed to a
+// failure to read it
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// asDecodeError marks err as a decode error, keeping EOF intact
+func asDecodeError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return &decodeError{err: err}
+}
+
+// sourceReader keeps the last error of reading the compressed or archived
+// bytes of a file, through r or ra
+type sourceReader struct {
+	r   io.Reader
+	ra  io.ReaderAt
+	err error
+}
+
+func (sr *sourceReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if err != nil {
+		sr.err = err
+	}
+	return n, err
+}
+
+func (sr *sourceReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := sr.ra.ReadAt(p, off)
+	if err != nil {
+		sr.err = err
+	}
+	return n, err
+}
+
+// decodeReader marks the errors of a decompressed stream or archive member as
+// decode errors, unless they come from reading its source or the source ended
+// too early
+type decodeReader struct {
+	r      io.Reader
+	source *sourceReader
+}
+
+func (dr *decodeReader) Read(p []byte) (int, error) {
+	n, err := dr.r.Read(p)
+	return n, dr.mark(err)
+}
+
+// mark marks err as a decode error unless it is an error of the source
+func (dr *decodeReader) mark(err error) error {
+	switch source := dr.source.err; {
+	case err == nil || err == io.EOF:
+		return err
+	case source == io.EOF && errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("compressed stream is truncated: %w", err)
+	case source != nil && source != io.EOF && errors.Is(err, source):
+		return err
+	default:
+		return asDecodeError(err)
+	}
+}
+
+// cancelled reports whether err comes from a cancelled context
+func cancelled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Categorize returns the category of an error returned by the processor
+func Categorize(err error) models.ErrorCategory {
+	var panicErr *panicError
+	var decodeErr *decodeError
+	switch {
+	case cancelled(err):
+		return models.ErrorCancelled
+	case errors.Is(err, errArchiveTooLarge), errors.Is(err, errTooManyMembers), errors.Is(err, errCompressionTooHigh), errors.Is(err, errExpandsTooLarge):
+		return models.ErrorLimit
+	case errors.As(err, &panicErr):
+		return models.ErrorPanic
+	case errors.Is(err, fs.ErrNotExist):
+		return models.ErrorNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return models.ErrorPermission
+	case errors.As(err, &decodeErr), errors.Is(err, zip.ErrFormat), errors.Is(err, tar.ErrHeader), errors.Is(err, errUnknownColumn), errors.Is(err, errUnknownFormat), errors.Is(err, errInvalidFilter):
+		return models.ErrorDecode
+	default:
+		return models.ErrorIO
+	}
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestDecompressorErrorCategories(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(generatorLog(64 * 1024))
+	writer.Close()
+	data := compressed.Bytes()
+
+	corrupt := bytes.Clone(data)
+	for i := 100; i < 200; i++ {
+		corrupt[i] ^= 0xff
+	}
+
+	tests := []struct {
+		name   string
+		source io.Reader
+		want   models.ErrorCategory
+	}{
+		{"corrupt stream", bytes.NewReader(corrupt), models.ErrorDecode},
+		{"truncated file", bytes.NewReader(data[:len(data)/2]), models.ErrorIO},
+		{"read error", io.MultiReader(bytes.NewReader(data[:len(data)/2]), iotest.ErrReader(syscall.EIO)), models.ErrorIO},
+		{"read error in header", iotest.ErrReader(syscall.EIO), models.ErrorIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decompressor, err := newDecompressor(tt.source, CompressionGzip)
+			if err == nil {
+				_, err = io.Copy(io.Discard, decompressor)
+				decompressor.Close()
+			}
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if got := Categorize(err); got != tt.want {
+				t.Errorf("Categorize(%v) = %s, want %s", err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// maxParseErrors limits how many parse errors are kept on a result
+const maxParseErrors = 10
+
+var (
+	errMissingTimestamp = errors.New("missing [timestamp]")
+	errMissingLevel     = errors.New("missing level")
+	errInvalidPayload   = errors.New("invalid payload")
+)
+
+// ParseLogEntry parses a line in the "[RFC3339] LEVEL message {json}" format
+// written by the log generator. On an invalid payload the entry is returned
+// with its message.
+func ParseLogEntry(line []byte) (models.LogEntry, error) {
+	entry, message, payload, err := parseLogLine(line)
+	if errors.Is(err, errInvalidPayload) {
+		entry.Message = string(message)
+	}
+	if err != nil {
+		return entry, err
+	}
+	return decodeLogEntry(entry, message, payload)
+}
+
+// parseLogLine parses the timestamp and level of a line in the generator's
+// format, returning its message and trailing JSON payload, if any, as bytes
+// of the line, so lines whose message and payload are not read are parsed
+// without allocating. A payload that is not valid JSON is left out of the
+// message and reported as errInvalidPayload.
+func parseLogLine(line []byte) (entry models.LogEntry, message, payload []byte, err error) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 || line[0] != '[' {
+		return entry, nil, nil, errMissingTimestamp
+	}
+	end := bytes.IndexByte(line, ']')
+	if end < 0 {
+		return entry, nil, nil, errMissingTimestamp
+	}
+
+	timestamp, err := parseTimestamp(line[1:end])
+	if err != nil {
+		return entry, nil, nil, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	entry.Timestamp = timestamp
+
+	rest := bytes.TrimLeft(line[end+1:], " \t")
+	levelEnd := bytes.IndexAny(rest, " \t")
+	if levelEnd < 0 {
+		levelEnd = len(rest)
+	}
+	entry.Level = lookupLevel(rest[:levelEnd])
+	if entry.Level == "" {
+		return entry, nil, nil, errMissingLevel
+	}
+
+	message = bytes.TrimLeft(rest[levelEnd:], " \t")
+	if start, valid := payloadStart(message); start >= 0 {
+		message, payload = bytes.TrimRight(message[:start], " \t"), message[start:]
+		if !valid {
+			return entry, message, nil, fmt.Errorf("%w: not valid JSON", errInvalidPayload)
+		}
+	}
+	return entry, message, payload, nil
+}
+
+// decodeLogEntry completes an entry parsed by parseLogLine with its message
+// and payload
+func decodeLogEntry(entry models.LogEntry, message, payload []byte) (models.LogEntry, error) {
+	entry.Message = string(message)
+	if payload == nil {
+		return entry, nil
+	}
+	decoded, err := decodePayload(payload)
+	if err != nil {
+		return entry, fmt.Errorf("%w: %v", errInvalidPayload, err)
+	}
+	entry.Payload = decoded
+	return entry, nil
+}
+
+// parseTimestamp parses an RFC3339 timestamp. UTC timestamps without fractional
+// seconds, as written by the generator, take an allocation free fast path.
+func parseTimestamp(b []byte) (time.Time, error) {
+	// 2006-01-02T15:04:05Z
+	if len(b) == 20 && b[4] == '-' && b[7] == '-' && b[10] == 'T' && b[13] == ':' && b[16] == ':' && b[19] == 'Z' {
+		year, ok1 := atoiBytes(b[0:4])
+		month, ok2 := atoiBytes(b[5:7])
+		day, ok3 := atoiBytes(b[8:10])
+		hour, ok4 := atoiBytes(b[11:13])
+		minute, ok5 := atoiBytes(b[14:16])
+		second, ok6 := atoiBytes(b[17:19])
+		if ok1 && ok2 && ok3 && ok4 && ok5 && ok6 &&
+			month >= 1 && month <= 12 && day >= 1 && day <= daysIn(time.Month(month), year) && hour < 24 && minute < 60 && second < 60 {
+			return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC), nil
+		}
+	}
+	return time.Parse(time.RFC3339, string(b))
+}
+
+// daysIn returns the number of days of a month, which time.Date would
+// otherwise roll days past into the next month
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func atoiBytes(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// payloadStart returns the offset of the trailing JSON object in a message, or
+// -1, and whether it is valid. Failing a valid object, an object that does not
+// end before the end of the message, such as one cut off by truncation, is
+// taken as an invalid payload.
+func payloadStart(message []byte) (int, bool) {
+	if len(message) > 0 && message[len(message)-1] == '}' {
+		for i := 0; i < len(message); i++ {
+			if message[i] != '{' || (i > 0 && message[i-1] != ' ') {
+				continue
+			}
+			if json.Valid(message[i:]) {
+				return i, true
+			}
+		}
+	}
+	for i := 0; i+1 < len(message); i++ {
+		if message[i] != '{' || message[i+1] != '"' || (i > 0 && message[i-1] != ' ') {
+			continue
+		}
+		var object json.RawMessage
+		if json.NewDecoder(bytes.NewReader(message[i:])).Decode(&object) != nil {
+			return i, false
+		}
+	}
+	return -1, false
+}
+
+func decodePayload(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var payload map[string]any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+)
+
+func TestParseLogEntryPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		payload map[string]any
+		invalid bool
+	}{
+		{name: "no payload", line: "[2025-04-01T10:00:00Z] INFO started", message: "started"},
+		{
+			name:    "payload",
+			line:    `[2025-04-01T10:00:00Z] INFO login {"userId":"u1"}`,
+			message: "login",
+			payload: map[string]any{"userId": "u1"},
+		},
+		{name: "braces in the message", line: "[2025-04-01T10:00:00Z] INFO map {a b}", message: "map {a b}"},
+		{name: "object in the message", line: `[2025-04-01T10:00:00Z] INFO got {"a":1} back`, message: `got {"a":1} back`},
+		{name: "invalid payload", line: `[2025-04-01T10:00:00Z] INFO login {"userId":}`, message: "login", invalid: true},
+		{name: "cut off payload", line: `[2025-04-01T10:00:00Z] INFO login {"userId":"u`, message: "login", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := ParseLogEntry([]byte(tt.line))
+			if errors.Is(err, errInvalidPayload) != tt.invalid || (err != nil && !tt.invalid) {
+				t.Fatalf("err = %v, want invalid payload %v", err, tt.invalid)
+			}
+			if entry.Message != tt.message || !reflect.DeepEqual(entry.Payload, tt.payload) {
+				t.Errorf("entry = %q %v, want %q %v", entry.Message, entry.Payload, tt.message, tt.payload)
+			}
+		})
+	}
+}
+
+// TestTruncatedPayload checks that a payload cut off by truncation leaves its
+// line intact, while an invalid payload makes it malformed
+func TestTruncatedPayload(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.MaxLineLength = 64
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `[2025-04-01T10:00:00Z] INFO login {"userId":"` + strings.Repeat("u", 100) + "\"}\n" +
+		`[2025-04-01T10:00:01Z] INFO login {"userId":}` + "\n"
+	result := scanString(t, p, FormatText, input, nil)
+	if result.OversizedLines != 1 || result.MalformedLines != 1 {
+		t.Fatalf("got %d oversized and %d malformed lines, want 1 and 1", result.OversizedLines, result.MalformedLines)
+	}
+	if want := []string{"line 2: invalid payload: not valid JSON"}; !reflect.DeepEqual(result.ParseErrors, want) {
+		t.Errorf("parse errors = %q, want %q", result.ParseErrors, want)
+	}
+}
+
+func TestParseTimestampMatchesRFC3339(t *testing.T) {
+	inputs := []string{
+		"2025-04-01T10:00:00Z",
+		"2024-02-29T23:59:59Z",
+		"2025-02-29T00:00:00Z",
+		"2025-02-31T00:00:00Z",
+		"2025-04-31T12:00:00Z",
+		"2025-12-31T00:00:00Z",
+		"2025-13-01T00:00:00Z",
+		"2025-04-01T24:00:00Z",
+		"2025-04-01T10:00:00.5Z",
+		"2025-04-01T10:00:00+02:00",
+	}
+	for _, input := range inputs {
+		want, wantErr := time.Parse(time.RFC3339, input)
+		got, err := parseTimestamp([]byte(input))
+		if (err != nil) != (wantErr != nil) || !got.Equal(want) {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v, %v", input, got, err, want, wantErr)
+		}
+	}
+}
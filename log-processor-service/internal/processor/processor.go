@@ -1,13 +1,16 @@
 package processor
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"regexp"
 	"sync"
+	"time"
 
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
 	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
 )
 
@@ -15,127 +18,698 @@ import (
 type Processor struct {
 	numWorkers int
 	uploadDir  string
+	classifier LineClassifier
+	entryStart *regexp.Regexp
+	bucketSize time.Duration
+	anomalies  *anomalyDetector
+	topN       int
+	fields     []string
+	topK       int
+
+	sampleSize int
+
+	chunkThreshold int64
+	chunkSize      int64
+	maxLine        int
+
+	maxArchiveMembers   int
+	maxArchiveBytes     int64
+	maxCompressionRatio int64
+
+	checkpointInterval time.Duration
+	followInterval     time.Duration
+	detectLines        int
+
+	jsonFields jsonFields
+}
+
+// JobOptions are the settings of a processing job that apply to all of its
+// files
+type JobOptions struct {
+	// Format is the format all files are read in, such as access or syslog.
+	// If empty it is told by the extension of each file.
+	Format string
+
+	// Columns maps the columns of CSV files, those left empty are detected
+	// from the header
+	Columns models.ColumnMapping
+
+	// Filters select the entries analysed, the lines of the others are
+	// counted as excluded
+	Filters models.Filters
+
+	// Index builds the inverted index of every file that is not an archive,
+	// saved next to the uploads for full-text search
+	Index bool
 }
 
 // ProgressCallback is a function type for reporting progress
 type ProgressCallback func(fileName string, progress int, status string, err error)
 
-// NewProcessor creates a new processor with the specified number of workers
-func NewProcessor(numWorkers int, uploadDir string) *Processor {
-	return &Processor{
-		numWorkers: numWorkers,
-		uploadDir:  uploadDir,
+// NewProcessor creates a new processor from the service configuration
+func NewProcessor(cfg *config.Config) (*Processor, error) {
+	classifier, err := NewClassifier(cfg.Classifier, cfg.ClassifierField, cfg.ClassifierRulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("error creating classifier: %v", err)
 	}
+
+	anomalies, err := newAnomalyDetector(cfg.AnomalyMethod, cfg.AnomalyThreshold, cfg.AnomalyWindow, cfg.AnomalyMinLines, cfg.TimelineBucket)
+	if err != nil {
+		return nil, fmt.Errorf("error creating anomaly detector: %v", err)
+	}
+
+	var entryStart *regexp.Regexp
+	if cfg.EntryStartPattern != "" {
+		if entryStart, err = regexp.Compile(cfg.EntryStartPattern); err != nil {
+			return nil, fmt.Errorf("invalid entry start pattern %q: %v", cfg.EntryStartPattern, err)
+		}
+	}
+
+	maxLine := cfg.MaxLineLength
+	if maxLine <= 0 {
+		maxLine = defaultMaxLineLength
+	}
+	detectLines := cfg.DetectSampleLines
+	if detectLines <= 0 {
+		detectLines = defaultDetectLines
+	}
+
+	return &Processor{
+		numWorkers: cfg.NumWorkers,
+		uploadDir:  cfg.UploadDir,
+		classifier: classifier,
+		entryStart: entryStart,
+		bucketSize: cfg.TimelineBucket,
+		anomalies:  anomalies,
+		topN:       cfg.TemplateTopN,
+		fields:     cfg.AggregateFields,
+		topK:       cfg.FieldTopK,
+
+		sampleSize: cfg.SampleLines,
+
+		chunkThreshold: cfg.ChunkThreshold,
+		chunkSize:      cfg.ChunkSize,
+		maxLine:        maxLine,
+
+		maxArchiveMembers:   cfg.MaxArchiveMembers,
+		maxArchiveBytes:     cfg.MaxArchiveBytes,
+		maxCompressionRatio: int64(cfg.MaxCompressionRatio),
+
+		checkpointInterval: cfg.CheckpointInterval,
+		followInterval:     cfg.FollowInterval,
+		detectLines:        detectLines,
+
+		jsonFields: jsonFields{
+			level:     cfg.JSONLevelFields,
+			timestamp: cfg.JSONTimestampFields,
+			message:   cfg.JSONMessageFields,
+		},
+	}, nil
 }
 
-// ProcessLogFile processes a single log file and returns the result
-func ProcessLogFile(filePath string, progressCb ProgressCallback) (models.Result, error) {
+// ProcessLogFile processes a single log file in one pass and returns the result.
+// For archives the total over all members is returned. If ctx is cancelled the
+// lines scanned so far are returned as a partial result along with ctx.Err().
+func (p *Processor) ProcessLogFile(ctx context.Context, filePath string, progressCb ProgressCallback) (models.Result, error) {
 	fmt.Printf("Opening file: %s\n", filePath)
-	file, err := os.Open(filePath)
+	fileName := filepath.Base(filePath)
+	job, err := p.inspectFile(filePath, "", progressCb)
 	if err != nil {
-		progressCb(filepath.Base(filePath), 0, "error", err)
-		return models.Result{}, fmt.Errorf("error opening file %s: %v", filePath, err)
+		progressCb(fileName, 0, "error", err)
+		return models.Result{}, err
 	}
-	defer file.Close()
 
-	result := models.Result{
-		FilePath: filePath,
+	if job.archive != "" {
+		results, err := p.scanArchive(ctx, job)
+		if cancelled(err) {
+			progressCb(fileName, job.progress.reported(), "cancelled", err)
+			return results[len(results)-1], err
+		}
+		if err != nil {
+			progressCb(fileName, 0, "error", err)
+			return models.Result{}, err
+		}
+		progressCb(fileName, 100, "completed", nil)
+		return results[len(results)-1], nil
 	}
 
-	// Get file size for progress calculation
-	fileInfo, err := file.Stat()
+	state, err := p.scanChunk(ctx, chunkTask{job: job, r: byteRange{start: 0, end: job.size}})
+	if cancelled(err) {
+		progressCb(fileName, job.progress.reported(), "cancelled", err)
+		result := state.finish(filePath, p.topN, p.topK)
+		result.MarkPartial()
+		return result, err
+	}
 	if err != nil {
-		progressCb(filepath.Base(filePath), 0, "error", err)
-		return result, fmt.Errorf("error getting file info: %v", err)
+		progressCb(fileName, 0, "error", err)
+		return models.Result{}, err
 	}
-	fileSize := fileInfo.Size()
 
-	scanner := bufio.NewScanner(file)
-	bytesRead := int64(0)
-	lastProgress := 0
+	// Report 100% completion
+	progressCb(fileName, 100, "completed", nil)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		bytesRead += int64(len(line) + 1) // +1 for newline
+	return state.finish(filePath, p.topN, p.topK), nil
+}
 
-		// Calculate progress percentage
-		progress := int((float64(bytesRead) / float64(fileSize)) * 100)
+// scanRange scans the lines of a byte range of a file into state
+func (p *Processor) scanRange(ctx context.Context, file *os.File, r byteRange, compression string, state *scanState, progress *fileProgress, cp *checkpointer) (*scanState, error) {
+	return p.scanStream(ctx, io.NewSectionReader(file, r.start, r.end-r.start), r.end-r.start, compression, state, progress, cp)
+}
 
-		// Only report progress if it has changed by at least 10%
-		if progress >= lastProgress+10 && progress != 100 {
-			lastProgress = progress
-			progressCb(filepath.Base(filePath), progress, "processing", nil)
+// scanStream scans the lines of a stream of the given size into state.
+// Compressed streams are decompressed on the fly within the limits of
+// archives and always scanned as a whole, with progress following the
+// compressed bytes consumed; the decompressed bytes state already covers are
+// skipped.
+func (p *Processor) scanStream(ctx context.Context, source io.Reader, size int64, compression string, state *scanState, progress *fileProgress, cp *checkpointer) (*scanState, error) {
+	if compression == "" {
+		return p.scanLines(ctx, source, state, progress, cp)
+	}
+
+	decompressor, err := newDecompressor(&countingReader{r: source, progress: progress}, compression)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressor.Close()
+	expanded := p.limitExpansion(decompressor, size)
+
+	if _, err := io.CopyN(io.Discard, expanded, state.result.TotalBytes); err != nil {
+		return nil, err
+	}
+
+	state, err = p.scanLines(ctx, expanded, state, nil, cp)
+	if state != nil {
+		state.result.Compression = compression
+	}
+	return state, err
+}
+
+// scanLines runs the analysis over every line of a plain stream, or every
+// record or value of a CSV or JSON stream, adding to state and reporting the
+// bytes scanned to progress and cp unless they are nil. When ctx is cancelled
+// the state of the lines scanned so far is returned along with ctx.Err().
+func (p *Processor) scanLines(ctx context.Context, source io.Reader, state *scanState, progress *fileProgress, cp *checkpointer) (*scanState, error) {
+	if err := ctx.Err(); err != nil {
+		return state, err
+	}
+
+	next := p.entryReader(source, state)
+	pending := int64(0)
+
+	for {
+		n, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		state.result.TotalBytes += int64(n)
+
+		pending += int64(n)
+		if pending >= progressStep {
+			if progress != nil {
+				progress.advance(pending)
+			}
+			pending = 0
+			if err := ctx.Err(); err != nil {
+				return state, err
+			}
+			cp.step(state)
 		}
+	}
+	if progress != nil {
+		progress.advance(pending)
+	}
 
-		if strings.Contains(line, "ERROR") {
-			result.ErrorCount++
-		} else if strings.Contains(line, "WARN") {
-			result.WarnCount++
+	return state, nil
+}
+
+// entryReader returns a function that reads the next line, CSV record or JSON
+// value of a stream into state and returns the number of bytes it occupied
+func (p *Processor) entryReader(source io.Reader, state *scanState) func() (int, error) {
+	switch {
+	case state.csv != nil:
+		reader := newCSVReader(source, p.maxLine)
+		return func() (int, error) {
+			n, err := reader.Next()
+			if err != nil {
+				return 0, err
+			}
+			return n, state.observeRecord(reader)
+		}
+	case state.json != nil:
+		reader := newJSONReader(source, p.maxLine)
+		return func() (int, error) {
+			n, err := reader.Next()
+			if err != nil {
+				return 0, err
+			}
+			state.observeValue(reader)
+			return n, nil
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		progressCb(filepath.Base(filePath), 0, "error", err)
-		return result, fmt.Errorf("error reading file %s: %v", filePath, err)
+	reader := newLineReader(source, p.maxLine)
+	return func() (int, error) {
+		line, n, err := reader.Next()
+		if err != nil {
+			return 0, err
+		}
+		state.observe(line, reader.Length())
+		return n, nil
 	}
+}
 
-	// Report 100% completion
-	progressCb(filepath.Base(filePath), 100, "completed", nil)
+// fileJob tracks the chunks of one file while they are being processed.
+// Archives are always a single chunk yielding one result per member.
+type fileJob struct {
+	filePath     string
+	size         int64
+	compression  string
+	archive      string
+	format       string
+	columns      models.ColumnMapping
+	filter       *entryFilter
+	buildIndex   bool
+	progressCb   ProgressCallback
+	progress     *fileProgress
+	checkpointCb CheckpointCallback
+	index        int
+	states       []*scanState
+	pending      int
+	err          error
+
+	// restoredLines and restoredBytes are the lines and bytes the chunks
+	// resumed from checkpoints had scanned
+	restoredLines int
+	restoredBytes int64
+}
+
+// chunkTask is a byte range of a file handed to a worker. A chunk resumed
+// from a checkpoint continues from the state saved, or is done already.
+type chunkTask struct {
+	job    *fileJob
+	index  int
+	r      byteRange
+	resume *scanState
+	done   bool
+}
 
-	return result, nil
+// chunkOutcome is the scan of a chunk reported back by a worker
+type chunkOutcome struct {
+	task    chunkTask
+	state   *scanState
+	results []models.Result
+	err     error
 }
 
-// ProcessFiles processes multiple log files concurrently
-func (p *Processor) ProcessFiles(fileNames []string, progressCb ProgressCallback) ([]models.Result, error) {
-	filePaths := make([]string, len(fileNames))
+// ProcessFiles processes multiple log files concurrently and returns an outcome
+// per file, in the order of fileNames. Files larger than the chunk threshold
+// are split into newline aligned chunks that are processed in parallel and
+// merged back into a single result per file. Archives yield a result per member
+// followed by the total of the archive. Once ctx is cancelled no further chunks
+// are started, files cut short get partial results and ctx.Err() is returned.
+//
+// Files continue from the checkpoints an earlier run of the same files saved
+// through checkpointCb, which if not nil is called with the progress of every
+// chunk every checkpoint interval, when it ends and when it is cancelled.
+func (p *Processor) ProcessFiles(ctx context.Context, fileNames []string, opts JobOptions, checkpoints []Checkpoint, progressCb ProgressCallback, checkpointCb CheckpointCallback) ([]FileOutcome, error) {
+	saved := make(map[string][]Checkpoint)
+	for _, checkpoint := range checkpoints {
+		saved[checkpoint.FileName] = append(saved[checkpoint.FileName], checkpoint)
+	}
+
+	// Invalid filters fail every file, like an unknown format
+	filter, filterErr := newEntryFilter(opts.Filters)
+
+	outcomes := make([]FileOutcome, len(fileNames))
+	var tasks []chunkTask
 	for i, fileName := range fileNames {
-		filePaths[i] = filepath.Join(p.uploadDir, fileName)
+		if err := ctx.Err(); err != nil {
+			progressCb(fileName, 0, "cancelled", err)
+			outcomes[i] = newFailedOutcome(fileName, err)
+			continue
+		}
+
+		filePath := filepath.Join(p.uploadDir, fileName)
+		var job *fileJob
+		var jobTasks []chunkTask
+		err := recovered(func() error {
+			if opts.Format != "" && !knownFormat(opts.Format) {
+				return fmt.Errorf("%w %q", errUnknownFormat, opts.Format)
+			}
+			if filterErr != nil {
+				return filterErr
+			}
+			var err error
+			if job, err = p.inspectFile(filePath, opts.Format, progressCb); err != nil {
+				return err
+			}
+			job.columns = opts.Columns
+			job.filter = filter
+			job.buildIndex = opts.Index
+			job.checkpointCb = checkpointCb
+			jobTasks, err = p.planJob(job, saved[filepath.Base(filePath)])
+			return err
+		})
+		if err != nil {
+			progressCb(filepath.Base(filePath), 0, "error", err)
+			fmt.Printf("Error processing file %s: %v\n", filePath, err)
+			outcomes[i] = newFailedOutcome(fileName, err)
+			continue
+		}
+
+		job.index = i
+		job.pending = len(jobTasks)
+		tasks = append(tasks, jobTasks...)
 	}
 
 	var wg sync.WaitGroup
-	fileChan := make(chan string, len(filePaths))
-	resultChan := make(chan models.Result, len(filePaths))
+	taskChan := make(chan chunkTask, len(tasks))
+	outcomeChan := make(chan chunkOutcome, len(tasks))
 
 	// Start workers
 	for i := range p.numWorkers {
 		fmt.Printf("Starting worker: %d\n", i)
-		go p.worker(fileChan, resultChan, &wg, progressCb)
+		wg.Add(1)
+		go p.worker(ctx, taskChan, outcomeChan, &wg)
 	}
 
-	// Send files to workers
-	for _, filePath := range filePaths {
-		wg.Add(1)
-		fmt.Printf("Adding file to channel: %s\n", filePath)
-		fileChan <- filePath
+	// Send chunks to workers
+	for _, task := range tasks {
+		fmt.Printf("Adding chunk %d of file to channel: %s\n", task.index, task.job.filePath)
+		taskChan <- task
 	}
-	close(fileChan)
+	close(taskChan)
 
-	// Close results channel when all workers are done
+	// Close outcome channel when all workers are done
 	go func() {
 		wg.Wait()
-		close(resultChan)
+		close(outcomeChan)
 	}()
 
-	// Collect results
+	// Collect results, completing a file once all of its chunks are in
+	for outcome := range outcomeChan {
+		job := outcome.task.job
+		if outcome.err != nil && job.err == nil {
+			job.err = outcome.err
+		}
+		job.states[outcome.task.index] = outcome.state
+		job.pending--
+		if job.pending > 0 {
+			continue
+		}
+
+		outcomes[job.index] = p.completeJob(job, outcome.results)
+	}
+
+	return outcomes, ctx.Err()
+}
+
+// completeJob reports the end of a file whose chunks are all in and builds its
+// outcome
+func (p *Processor) completeJob(job *fileJob, archiveResults []models.Result) FileOutcome {
+	fileName := filepath.Base(job.filePath)
+	err := job.err
+
 	var results []models.Result
-	for result := range resultChan {
-		results = append(results, result)
-		fmt.Println(result.String())
+	if err == nil || cancelled(err) {
+		// A panic while merging only fails this file
+		if mergeErr := recovered(func() error {
+			results = p.jobResults(job, archiveResults)
+			return nil
+		}); mergeErr != nil {
+			err, results = mergeErr, nil
+		}
 	}
 
-	return results, nil
+	outcome := FileOutcome{FileName: fileName, Results: results, Err: err}
+	if len(results) > 0 {
+		outcome.RestoredLines, outcome.RestoredBytes = job.restoredLines, job.restoredBytes
+	}
+	switch {
+	case err == nil:
+		job.progressCb(fileName, 100, "completed", nil)
+	case cancelled(err):
+		outcome.Category = models.ErrorCancelled
+		job.progressCb(fileName, job.progress.reported(), "cancelled", err)
+	default:
+		outcome.Category = Categorize(err)
+		job.progressCb(fileName, 0, "error", err)
+		fmt.Printf("Error processing file %s: %v\n", job.filePath, err)
+	}
+	return outcome
 }
 
-// worker processes files from the input channel
-func (p *Processor) worker(filePaths <-chan string, results chan<- models.Result, wg *sync.WaitGroup, progressCb ProgressCallback) {
-	for filePath := range filePaths {
-		fmt.Printf("Worker processing file: %s\n", filePath)
-		result, err := ProcessLogFile(filePath, progressCb)
-		if err != nil {
-			fmt.Printf("Error processing file %s: %v\n", filePath, err)
+// jobResults merges the chunks of a file into its result. A cancelled file
+// lacks the chunks that were never started and has no result if none was.
+func (p *Processor) jobResults(job *fileJob, archiveResults []models.Result) []models.Result {
+	if job.archive != "" {
+		return archiveResults
+	}
+
+	var state *scanState
+	for _, next := range job.states {
+		if next == nil {
+			continue
+		}
+		if state == nil {
+			state = next
+			continue
+		}
+		state.merge(next)
+	}
+	if state == nil {
+		return nil
+	}
+
+	result := state.finish(job.filePath, p.topN, p.topK)
+	if job.err != nil {
+		result.MarkPartial()
+	}
+	switch {
+	case !job.buildIndex:
+	case job.err != nil:
+		result.IndexError = "the file was not indexed as its processing was cancelled"
+	case state.index == nil:
+		result.IndexError = "the part of the file scanned before it was resumed could not be indexed"
+	default:
+		if err := p.writeIndex(job, state.index); err != nil {
+			result.IndexError = "the index could not be saved"
 		} else {
-			results <- result
+			result.Indexed = true
+		}
+	}
+	fmt.Println(result.String())
+	return []models.Result{result}
+}
+
+// inspectFile detects the size, compression and archive format of a file, and
+// the format of files that are not archives unless one is requested. Files
+// whose extension names no format are read in the format their head parses
+// best in.
+func (p *Processor) inspectFile(filePath, format string, progressCb ProgressCallback) (*fileJob, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error getting file info: %w", err)
+	}
+	fileSize := fileInfo.Size()
+
+	compression, err := detectCompression(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+
+	archive, err := detectArchive(file, fileSize, compression)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+
+	if format == "" && archive == "" {
+		format = formatOf(filePath)
+		if format == FormatText {
+			detection, err := p.detectStream(file, compression, format)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file %s: %w", filePath, err)
+			}
+			format = detection.Format
+		}
+	}
+
+	return &fileJob{
+		filePath:    filePath,
+		size:        fileSize,
+		compression: compression,
+		archive:     archive,
+		format:      format,
+		progressCb:  progressCb,
+		progress:    newFileProgress(filepath.Base(filePath), fileSize, progressCb),
+	}, nil
+}
+
+// planJob splits a file into chunks, or resumes the chunks of an earlier run
+func (p *Processor) planJob(job *fileJob, saved []Checkpoint) ([]chunkTask, error) {
+	if tasks := p.resumeTasks(job, saved); tasks != nil {
+		job.states = make([]*scanState, len(tasks))
+		for _, task := range tasks {
+			if task.resume != nil {
+				job.restoredLines += task.resume.result.TotalLines
+				job.restoredBytes += task.resume.result.TotalBytes
+			}
+		}
+		return tasks, nil
+	}
+
+	ranges, err := p.splitJob(job)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]chunkTask, len(ranges))
+	for i, r := range ranges {
+		tasks[i] = chunkTask{job: job, index: i, r: r}
+	}
+	job.states = make([]*scanState, len(tasks))
+
+	// Save how the file was split before scanning, so a resumed run splits it the same way
+	if job.checkpointCb != nil && job.archive == "" {
+		for _, task := range tasks {
+			job.checkpointCb(newCheckpoint(task, nil, false))
+		}
+	}
+	return tasks, nil
+}
+
+// splitJob splits plain files larger than the chunk threshold into chunks
+func (p *Processor) splitJob(job *fileJob) ([]byteRange, error) {
+	// Compressed streams and archives cannot be entered in the middle
+	if job.compression != "" || job.archive != "" || job.size <= p.chunkThreshold {
+		return []byteRange{{start: 0, end: job.size}}, nil
+	}
+
+	file, err := os.Open(job.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", job.filePath, err)
+	}
+	defer file.Close()
+
+	if !splittable(job.format, file) {
+		return []byteRange{{start: 0, end: job.size}}, nil
+	}
+	return splitFile(file, job.size, p.chunkSize)
+}
+
+// worker scans file chunks from the input channel, skipping them once ctx is cancelled
+func (p *Processor) worker(ctx context.Context, tasks <-chan chunkTask, outcomes chan<- chunkOutcome, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for task := range tasks {
+		if err := ctx.Err(); err != nil {
+			outcomes <- chunkOutcome{task: task, err: err}
+			continue
+		}
+		fmt.Printf("Worker processing chunk %d of file: %s\n", task.index, task.job.filePath)
+
+		// A panic only fails the file it happened in
+		outcome := chunkOutcome{task: task}
+		outcome.err = recovered(func() error {
+			var err error
+			if task.job.archive != "" {
+				outcome.results, err = p.scanArchive(ctx, task.job)
+			} else {
+				outcome.state, err = p.scanChunk(ctx, task)
+			}
+			return err
+		})
+		outcomes <- outcome
+	}
+}
+
+// scanChunk scans a chunk, continuing from its checkpoint if it is resumed
+func (p *Processor) scanChunk(ctx context.Context, task chunkTask) (*scanState, error) {
+	state := task.resume
+	if state == nil {
+		state = p.newFormatState(task.job.format, task.job.columns, task.job.filter)
+		state.atStart = task.r.start == 0
+		if task.job.buildIndex {
+			state.index = newIndexBuilder()
+		}
+	}
+	state.offset = task.r.start
+	if task.resume != nil && task.job.buildIndex {
+		index, err := p.rebuildIndex(ctx, task)
+		if cancelled(err) {
+			return state, err
+		}
+		if err != nil {
+			fmt.Printf("Error indexing chunk %d of %s: %v\n", task.index, task.job.filePath, err)
+		}
+		state.index = index
+	}
+	if task.done {
+		task.job.progress.advance(task.r.end - task.r.start)
+		return state, nil
+	}
+
+	file, err := os.Open(task.job.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", task.job.filePath, err)
+	}
+	defer file.Close()
+
+	// Plain files continue right after the bytes already scanned, compressed
+	// streams skip them once decompressed
+	r := task.r
+	if task.job.compression == "" {
+		r.start += state.result.TotalBytes
+		task.job.progress.advance(state.result.TotalBytes)
+	}
+
+	cp := p.newCheckpointer(task)
+	state, err = p.scanRange(ctx, file, r, task.job.compression, state, task.job.progress, cp)
+	if err != nil && !cancelled(err) {
+		return nil, fmt.Errorf("error reading file %s: %w", task.job.filePath, err)
+	}
+	cp.flush(state, err == nil)
+	return state, err
+}
+
+// rebuildIndex indexes the bytes of a resumed chunk scanned before its
+// checkpoint, which does not hold the index, checking they are the lines of
+// the restored state
+func (p *Processor) rebuildIndex(ctx context.Context, task chunkTask) (*indexBuilder, error) {
+	file, err := os.Open(task.job.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", task.job.filePath, err)
+	}
+	defer file.Close()
+
+	resumed := task.resume
+	state := p.newFormatState(resumed.format, task.job.columns, task.job.filter)
+	state.atStart = task.r.start == 0
+	state.offset = task.r.start
+	state.index = newIndexBuilder()
+
+	var source io.Reader = io.NewSectionReader(file, task.r.start, task.r.end-task.r.start)
+	if task.job.compression != "" {
+		decompressor, err := newDecompressor(source, task.job.compression)
+		if err != nil {
+			return nil, err
 		}
-		wg.Done()
+		defer decompressor.Close()
+		source = decompressor
+	}
+	if _, err := p.scanLines(ctx, io.LimitReader(source, resumed.result.TotalBytes), state, nil, nil); err != nil {
+		return nil, err
+	}
+	if state.lines != resumed.lines {
+		return nil, fmt.Errorf("indexed %d lines, the checkpoint holds %d", state.lines, resumed.lines)
 	}
+	return state.index, nil
 }
@@ -0,0 +1,212 @@
+package processor
+
+import (
+	"sort"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// maxSampleLength bounds the text kept of a sample line
+const maxSampleLength = 1024
+
+// lineSample is a line kept by a lineSampler
+type lineSample struct {
+	line     int
+	offset   int64
+	text     string
+	priority uint64
+}
+
+// levelSamples are the first lines of a level and the lines of the lowest
+// priority, which is a hash of their offset. Unlike a reservoir, picking the
+// lowest priorities is random without depending on the order lines are seen
+// in, so chunks of a file can be sampled apart and merged.
+type levelSamples struct {
+	first  []lineSample
+	random []lineSample
+	worst  int // index of the highest priority in random, once it is full
+}
+
+// lineSampler keeps up to size of the first lines of every level and up to
+// size random ones among the rest. Line numbers are counted within the range
+// of the scan, offsets within the file.
+type lineSampler struct {
+	size   int
+	levels map[string]*levelSamples
+}
+
+// newLineSampler returns a sampler keeping size lines of each kind per level,
+// or nil if size is not positive
+func newLineSampler(size int) *lineSampler {
+	if size <= 0 {
+		return nil
+	}
+	return &lineSampler{size: size, levels: make(map[string]*levelSamples)}
+}
+
+// Add samples a line of the given level
+func (s *lineSampler) Add(level string, line int, offset int64, text []byte) {
+	if s == nil || level == "" {
+		return
+	}
+	samples, ok := s.levels[level]
+	if !ok {
+		samples = &levelSamples{}
+		s.levels[level] = samples
+	}
+
+	priority := samplePriority(offset)
+	first := len(samples.first) < s.size
+	// Random samples are drawn from twice the size, so size of them remain
+	// once those that are among the first lines are left out
+	random := len(samples.random) < 2*s.size || priority < samples.random[samples.worst].priority
+	if !first && !random {
+		return
+	}
+
+	sample := lineSample{line: line, offset: offset, text: sampleText(text), priority: priority}
+	if first {
+		samples.first = append(samples.first, sample)
+	}
+	if random {
+		samples.addRandom(sample, 2*s.size)
+	}
+}
+
+// addRandom keeps a sample among the limit of lowest priority
+func (l *levelSamples) addRandom(sample lineSample, limit int) {
+	if len(l.random) < limit {
+		l.random = append(l.random, sample)
+	} else if sample.priority < l.random[l.worst].priority {
+		l.random[l.worst] = sample
+	} else {
+		return
+	}
+	if len(l.random) == limit {
+		l.worst = 0
+		for i, kept := range l.random {
+			if kept.priority > l.random[l.worst].priority {
+				l.worst = i
+			}
+		}
+	}
+}
+
+// Merge adds the samples of the range directly following this one, which
+// starts after lines lines
+func (s *lineSampler) Merge(other *lineSampler, lines int) {
+	if s == nil || other == nil {
+		return
+	}
+	for level, next := range other.levels {
+		samples, ok := s.levels[level]
+		if !ok {
+			samples = &levelSamples{}
+			s.levels[level] = samples
+		}
+		for _, sample := range next.first {
+			if len(samples.first) < s.size {
+				sample.line += lines
+				samples.first = append(samples.first, sample)
+			}
+		}
+		for _, sample := range next.random {
+			sample.line += lines
+			samples.addRandom(sample, 2*s.size)
+		}
+	}
+}
+
+// Samples returns the samples of every level, ordered by level and line
+func (s *lineSampler) Samples() []models.Sample {
+	if s == nil {
+		return nil
+	}
+	levels := make([]string, 0, len(s.levels))
+	for level := range s.levels {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	var result []models.Sample
+	for _, level := range levels {
+		samples := s.levels[level]
+		first := make(map[int64]bool, len(samples.first))
+		var picked []models.Sample
+		for _, sample := range samples.first {
+			first[sample.offset] = true
+			picked = append(picked, sample.model(level, false))
+		}
+
+		random := append([]lineSample(nil), samples.random...)
+		sort.Slice(random, func(i, j int) bool { return random[i].priority < random[j].priority })
+		count := 0
+		for _, sample := range random {
+			if count < s.size && !first[sample.offset] {
+				picked = append(picked, sample.model(level, true))
+				count++
+			}
+		}
+
+		sort.Slice(picked, func(i, j int) bool { return picked[i].Line < picked[j].Line })
+		result = append(result, picked...)
+	}
+	return result
+}
+
+func (l lineSample) model(level string, random bool) models.Sample {
+	return models.Sample{Level: level, Line: l.line, Offset: l.offset, Text: l.text, Random: random}
+}
+
+// snapshot returns the samples kept, random ones marked as such
+func (s *lineSampler) snapshot() []models.Sample {
+	if s == nil {
+		return nil
+	}
+	var snapshot []models.Sample
+	for level, samples := range s.levels {
+		for _, sample := range samples.first {
+			snapshot = append(snapshot, sample.model(level, false))
+		}
+		for _, sample := range samples.random {
+			snapshot = append(snapshot, sample.model(level, true))
+		}
+	}
+	return snapshot
+}
+
+// restore adds the samples of a snapshot to an empty sampler
+func (s *lineSampler) restore(snapshot []models.Sample) {
+	if s == nil {
+		return
+	}
+	for _, saved := range snapshot {
+		samples, ok := s.levels[saved.Level]
+		if !ok {
+			samples = &levelSamples{}
+			s.levels[saved.Level] = samples
+		}
+		sample := lineSample{line: saved.Line, offset: saved.Offset, text: saved.Text, priority: samplePriority(saved.Offset)}
+		if saved.Random {
+			samples.addRandom(sample, 2*s.size)
+		} else if len(samples.first) < s.size {
+			samples.first = append(samples.first, sample)
+		}
+	}
+}
+
+// samplePriority hashes an offset with the finalizer of splitmix64
+func samplePriority(offset int64) uint64 {
+	x := uint64(offset) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// sampleText returns the text of a line, cut to the maximum sample length
+func sampleText(line []byte) string {
+	if len(line) > maxSampleLength {
+		line = line[:maxSampleLength]
+	}
+	return string(line)
+}
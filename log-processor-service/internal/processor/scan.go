@@ -0,0 +1,256 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// parseError is a line that could not be parsed, numbered within its scan
+type parseError struct {
+	line int
+	err  error
+}
+
+// scanState accumulates the analysis of a contiguous range of lines of a file.
+// States of consecutive ranges can be merged, so a file split into chunks yields
+// the same result as a single pass.
+type scanState struct {
+	classifier LineClassifier
+	result     models.Result
+	lines      int
+
+	parseErrors []parseError
+	timeline    *timelineBuilder
+	anomalies   *anomalyDetector
+	templates   *templateMiner
+	fields      *fieldAggregator
+	exceptions  *exceptionAggregator
+	samples     *lineSampler
+	index       *indexBuilder
+
+	// offset is the byte offset of the range in the file, which locates
+	// the samples and indexed lines
+	offset int64
+
+	// Lines matching entryStart start an entry, others continue it; every
+	// line is an entry if it is nil. atStart is set for ranges starting
+	// the file, head holds the continuation lines before the first entry
+	// of other ranges and entry the last entry, which may continue in the
+	// next range.
+	entryStart *regexp.Regexp
+	atStart    bool
+	head       pendingEntry
+	entry      *pendingEntry
+
+	// format is the format of the file, parser parses its lines unless it
+	// is in the generator's format. csv is the layout of CSV files and json
+	// the fields of JSON files, which are read by record or value instead of
+	// by line.
+	format string
+	parser LineParser
+	csv    *csvLayout
+	json   *jsonFields
+
+	// filter excludes the entries failing the filters of the job, nil if
+	// it has none
+	filter *entryFilter
+}
+
+func (p *Processor) newScanState() *scanState {
+	return &scanState{
+		classifier: p.classifier,
+		timeline:   newTimelineBuilder(p.bucketSize),
+		anomalies:  p.anomalies,
+		templates:  newTemplateMiner(),
+		fields:     newFieldAggregator(p.fields),
+		exceptions: newExceptionAggregator(),
+		samples:    newLineSampler(p.sampleSize),
+		entryStart: p.entryStart,
+		atStart:    true,
+	}
+}
+
+// observe classifies and analyses a single line. Lines over the maximum line
+// length arrive truncated, length is their full length. Continuation lines are
+// only kept for the stack trace of their entry, which is analysed by its
+// first line.
+func (s *scanState) observe(line []byte, length int) {
+	s.lines++
+	result := &s.result
+	s.index.Add(s.lines, s.offset+result.TotalBytes, line)
+	result.TotalLines++
+	result.LongestLine = max(result.LongestLine, length)
+	truncated := length > len(line)
+	if truncated {
+		result.OversizedLines++
+	}
+
+	if s.entryStart != nil {
+		if !s.startsEntry(line) {
+			s.continueEntry(line)
+			return
+		}
+		s.finishEntry()
+	}
+
+	// Parsers of formats that carry a level read it, lines without one and
+	// lines of the generator's format are classified. The message of the
+	// generator's format is only copied and its payload only decoded for the
+	// filters and field aggregation, so most of its lines are analysed
+	// without allocating.
+	var entry models.LogEntry
+	var message, payload []byte
+	var err error
+	var level string
+	blank := len(bytes.TrimSpace(line)) == 0
+	if !blank {
+		if s.parser != nil {
+			entry, err = s.parser.Parse(line)
+			level = entry.Level
+		} else {
+			entry, message, payload, err = parseLogLine(line)
+			if truncated && errors.Is(err, errInvalidPayload) {
+				// Only the payload was cut off, the entry itself is intact
+				err = nil
+			}
+			if err == nil && (s.filter.readsEntries() || (s.fields != nil && payload != nil)) {
+				entry, err = decodeLogEntry(entry, message, payload)
+			}
+		}
+	}
+	if level == "" {
+		level = s.classifier.Classify(line)
+	}
+	var parsed *models.LogEntry
+	if !blank && err == nil {
+		parsed = &entry
+	}
+
+	excluded := s.filter.exclude(parsed, level, line)
+	if s.entryStart != nil {
+		if s.entry == nil {
+			s.entry = &pendingEntry{}
+		}
+		s.entry.reset(line, level)
+		s.entry.excluded = excluded
+	}
+	if excluded != "" {
+		result.AddExcluded(excluded, 1)
+		return
+	}
+	result.Entries++
+	s.countLevel(level)
+
+	if blank {
+		return
+	}
+	// Stack traces printed without a timestamp, such as Go panics, are
+	// entries whose trace is analysed rather than malformed lines
+	if err != nil && !startsStackTrace(line) {
+		s.malformed(s.lines, err)
+	} else if err == nil && !entry.Timestamp.IsZero() {
+		result.AddTimestamp(entry.Timestamp)
+	}
+
+	s.fields.Add(parsed, level)
+
+	if level == "" {
+		return
+	}
+	s.samples.Add(level, s.lines, s.offset+result.TotalBytes, line)
+	if parsed != nil && !parsed.Timestamp.IsZero() {
+		s.timeline.Add(parsed.Timestamp, level)
+	}
+	switch {
+	case s.parser == nil && parsed != nil:
+		s.templates.AddMessage(level, message, payload != nil, line)
+	case parsed != nil && parsed.Message != "":
+		// The payload of parsed formats holds the fields of every line,
+		// their templates are of the message or of the whole line
+		s.templates.Add(level, &models.LogEntry{Message: parsed.Message}, line)
+	default:
+		s.templates.Add(level, nil, line)
+	}
+}
+
+// countLevel counts an entry of the given level, which is empty if it has none
+func (s *scanState) countLevel(level string) {
+	if level != "" {
+		s.result.AddLevel(level, 1)
+	}
+	switch level {
+	case LevelError:
+		s.result.ErrorCount++
+	case LevelWarn:
+		s.result.WarnCount++
+	}
+}
+
+// malformed counts an entry that could not be parsed, keeping the first few
+// reasons
+func (s *scanState) malformed(line int, err error) {
+	s.result.MalformedLines++
+	if len(s.parseErrors) < maxParseErrors {
+		s.parseErrors = append(s.parseErrors, parseError{line: line, err: err})
+	}
+}
+
+// merge appends the state of the range directly following this one
+func (s *scanState) merge(next *scanState) {
+	// The entry continuing into the next range ends before its lines
+	s.mergeEntries(next)
+	for _, parseErr := range next.parseErrors {
+		if len(s.parseErrors) >= maxParseErrors {
+			break
+		}
+		s.parseErrors = append(s.parseErrors, parseError{line: s.lines + parseErr.line, err: parseErr.err})
+	}
+	s.samples.Merge(next.samples, s.lines)
+	s.index = s.index.Merge(next.index, s.lines)
+	s.lines += next.lines
+
+	s.result.Add(next.result)
+	s.timeline.Merge(next.timeline)
+	s.templates.Merge(next.templates)
+	s.fields.Merge(next.fields)
+}
+
+// finish builds the result of the scanned lines
+func (s *scanState) finish(filePath string, topN, topK int) models.Result {
+	result := s.result
+	result.FilePath = filePath
+	result.Status = models.StatusCompleted
+	result.Classifier = s.classifier.Name()
+	result.Rules = s.classifier.Rules()
+	for _, parseErr := range s.parseErrors {
+		result.ParseErrors = append(result.ParseErrors, fmt.Sprintf("line %d: %v", parseErr.line, parseErr.err))
+	}
+	result.Timeline = s.timeline.Timeline()
+	result.Anomalies = s.anomalies.Detect(result.Timeline)
+	result.Templates = s.templates.Top(topN)
+	result.Fields = s.fields.Stats(topK)
+	result.Samples = s.samples.Samples()
+	result.Format = s.format
+	if s.csv != nil {
+		result.Columns = s.csv.columns()
+	}
+
+	// The last entry is complete once the file is, but a followed file may
+	// still add to it
+	var pending *stackTrace
+	var level string
+	if trace, ok := s.entry.trace(); ok {
+		pending, level = &trace, s.entry.level
+	} else if s.entry != nil && s.entry.unparsed > 0 {
+		result.MalformedLines += s.entry.unparsed
+		if len(result.ParseErrors) < maxParseErrors {
+			result.ParseErrors = append(result.ParseErrors, fmt.Sprintf("line %d: %v", s.entry.first, errUnparsedContinuation))
+		}
+	}
+	result.Exceptions = s.exceptions.Stats(pending, level)
+	return result
+}
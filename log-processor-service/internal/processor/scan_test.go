@@ -0,0 +1,559 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// generatorFileSize matches the size of the files written by the log generator
+const generatorFileSize = 60 * 1024 * 1024
+
+var (
+	generatorOnce sync.Once
+	generatorData []byte
+)
+
+// generatorLog returns size bytes of lines in the log generator's format
+func generatorLog(size int) []byte {
+	levels := []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+	messages := map[string]string{
+		"INFO":  "User authentication successful",
+		"FATAL": "Database connection failed",
+		"WARN":  "API request failed: timeout",
+		"ERROR": "Invalid request format detected",
+		"DEBUG": "Payment process started",
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	timestamp := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	buf.Grow(size + 256)
+	for buf.Len() < size {
+		level := levels[rng.Intn(len(levels))]
+		payload := ""
+		if rng.Intn(100) > 70 {
+			data, _ := json.Marshal(map[string]any{
+				"userId": rng.Intn(1000),
+				"ip":     fmt.Sprintf("192.168.%d.%d", rng.Intn(256), rng.Intn(256)),
+			})
+			payload = " " + string(data)
+		}
+		fmt.Fprintf(&buf, "[%s] %s %s%s\n", timestamp.Format(time.RFC3339), level, messages[level], payload)
+		if rng.Intn(50) == 0 {
+			timestamp = timestamp.Add(time.Second)
+		}
+	}
+	return buf.Bytes()
+}
+
+func generatorFile() []byte {
+	generatorOnce.Do(func() {
+		generatorData = generatorLog(generatorFileSize)
+	})
+	return generatorData
+}
+
+func TestLineReaderMatchesScanner(t *testing.T) {
+	inputs := map[string][]byte{
+		"generator":        generatorLog(1024 * 1024),
+		"crlf":             []byte("[2025-04-01T10:00:00Z] ERROR a\r\n[2025-04-01T10:00:01Z] WARN b\r\n"),
+		"no final newline": []byte("ERROR first\n\nwarn: last"),
+		"empty":            {},
+		"long line":        []byte(strings.Repeat("x", readBufferSize+10) + " ERROR\nWARN\n"),
+	}
+	classifier := NewWordClassifier()
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			var want []string
+			scanner := bufio.NewScanner(bytes.NewReader(input))
+			scanner.Buffer(make([]byte, 4096), 2*readBufferSize)
+			for scanner.Scan() {
+				want = append(want, scanner.Text()+"|"+classifier.Classify(scanner.Bytes()))
+			}
+
+			// One byte reads exercise the buffer refills
+			reader := newLineReader(iotest.OneByteReader(bytes.NewReader(input)), 2*readBufferSize)
+			var got []string
+			consumed := 0
+			for {
+				line, n, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				consumed += n
+				got = append(got, string(line)+"|"+classifier.Classify(line))
+			}
+
+			if consumed != len(input) {
+				t.Errorf("consumed %d bytes, want %d", consumed, len(input))
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("lines differ from bufio.Scanner:\ngot  %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLineReaderTruncatesLongLines(t *testing.T) {
+	input := "ERROR " + strings.Repeat("x", 100) + "\r\nWARN short\n" + strings.Repeat("y", 80)
+
+	// One byte reads make the reader skip the rest of a line across refills
+	reader := newLineReader(iotest.OneByteReader(strings.NewReader(input)), 50)
+	want := []struct {
+		line   string
+		length int
+	}{
+		{"ERROR " + strings.Repeat("x", 44), 106},
+		{"WARN short", 10},
+		{strings.Repeat("y", 50), 80},
+	}
+
+	consumed := 0
+	for _, w := range want {
+		line, n, err := reader.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(line) != w.line || reader.Length() != w.length {
+			t.Errorf("got %q of length %d, want %q of length %d", line, reader.Length(), w.line, w.length)
+		}
+		consumed += n
+	}
+	if _, _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("got error %v, want EOF", err)
+	}
+	if consumed != len(input) {
+		t.Errorf("consumed %d bytes, want %d", consumed, len(input))
+	}
+}
+
+// BenchmarkScanLegacy is the scanning loop ProcessLogFile used to run:
+// a string per line and two substring searches
+func BenchmarkScanLegacy(b *testing.B) {
+	data := generatorFile()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		errors, warns := 0, 0
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.Contains(line, "ERROR") {
+				errors++
+			} else if strings.Contains(line, "WARN") {
+				warns++
+			}
+		}
+		if errors == 0 || warns == 0 {
+			b.Fatal("no levels found")
+		}
+	}
+}
+
+func benchmarkScanCore(b *testing.B, classifier LineClassifier) {
+	data := generatorFile()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		errors, warns := 0, 0
+		reader := newLineReader(bytes.NewReader(data), defaultMaxLineLength)
+		for {
+			line, _, err := reader.Next()
+			if err != nil {
+				break
+			}
+			switch classifier.Classify(line) {
+			case LevelError:
+				errors++
+			case LevelWarn:
+				warns++
+			}
+		}
+		if errors == 0 || warns == 0 {
+			b.Fatal("no levels found")
+		}
+	}
+}
+
+func BenchmarkScanCoreWord(b *testing.B) {
+	benchmarkScanCore(b, NewWordClassifier())
+}
+
+func BenchmarkScanCoreLevelToken(b *testing.B) {
+	benchmarkScanCore(b, NewLevelTokenClassifier(1))
+}
+
+// writeGeneratorFile writes a generator sized file for benchmarks
+func writeGeneratorFile(b *testing.B) string {
+	filePath := filepath.Join(b.TempDir(), "app.log")
+	if err := os.WriteFile(filePath, generatorFile(), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return filePath
+}
+
+// legacyProcessLogFile is ProcessLogFile as it was before the scanning core:
+// a string per line and two substring searches, reporting progress every 10%
+func legacyProcessLogFile(filePath string, progressCb ProgressCallback) (models.Result, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return models.Result{}, err
+	}
+	defer file.Close()
+
+	result := models.Result{FilePath: filePath}
+	info, err := file.Stat()
+	if err != nil {
+		return result, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	bytesRead := int64(0)
+	lastProgress := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		bytesRead += int64(len(line) + 1)
+		progress := int((float64(bytesRead) / float64(info.Size())) * 100)
+		if progress >= lastProgress+10 && progress != 100 {
+			lastProgress = progress
+			progressCb(filepath.Base(filePath), progress, "processing", nil)
+		}
+		if strings.Contains(line, "ERROR") {
+			result.ErrorCount++
+		} else if strings.Contains(line, "WARN") {
+			result.WarnCount++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	progressCb(filepath.Base(filePath), 100, "completed", nil)
+	return result, nil
+}
+
+// BenchmarkProcessLogFileLegacy runs the legacy ProcessLogFile end to end on a
+// generator sized file, the baseline of BenchmarkProcessLogFile
+func BenchmarkProcessLogFileLegacy(b *testing.B) {
+	filePath := writeGeneratorFile(b)
+	noProgress := func(string, int, string, error) {}
+
+	b.SetBytes(int64(len(generatorFile())))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if _, err := legacyProcessLogFile(filePath, noProgress); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkProcessLogFile(b *testing.B, configure func(*config.Config)) {
+	filePath := writeGeneratorFile(b)
+	cfg := config.NewConfig()
+	cfg.UploadDir = filepath.Dir(filePath)
+	configure(cfg)
+	p, err := NewProcessor(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	noProgress := func(string, int, string, error) {}
+
+	b.SetBytes(int64(len(generatorFile())))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if _, err := p.ProcessLogFile(context.Background(), filePath, noProgress); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProcessLogFile runs the full analysis of a generator sized file.
+// Aggregating the payload fields decodes the payloads, which allocates.
+func BenchmarkProcessLogFile(b *testing.B) {
+	benchmarkProcessLogFile(b, func(*config.Config) {})
+}
+
+// BenchmarkProcessLogFileWithoutFields runs the analysis without field
+// aggregation, which leaves the payloads undecoded
+func BenchmarkProcessLogFileWithoutFields(b *testing.B) {
+	benchmarkProcessLogFile(b, func(cfg *config.Config) { cfg.AggregateFields = nil })
+}
+
+// scanString scans input in a format as a whole file, or as chunks split
+// after each of the given line ends merged back together
+func scanString(t *testing.T, p *Processor, format, input string, filter *entryFilter, splits ...int) models.Result {
+	t.Helper()
+	var state *scanState
+	start := 0
+	for _, end := range append(splits, len(input)) {
+		chunk := p.newFormatState(format, models.ColumnMapping{}, filter)
+		chunk.atStart = start == 0
+		chunk.offset = int64(start)
+		chunk, err := p.scanLines(context.Background(), strings.NewReader(input[start:end]), chunk, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state == nil {
+			state = chunk
+		} else {
+			state.merge(chunk)
+		}
+		start = end
+	}
+	return state.finish("app.log", 10, 10)
+}
+
+// lineEnds returns the offsets after every newline of input but the last
+func lineEnds(input string) []int {
+	var ends []int
+	for i := 0; i < len(input)-1; i++ {
+		if input[i] == '\n' {
+			ends = append(ends, i+1)
+		}
+	}
+	return ends
+}
+
+func TestContinuationLines(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		entries      int
+		continuation int
+		malformed    int
+		parseErrors  []string
+		exceptions   int
+	}{
+		{
+			name:         "stack trace",
+			input:        "[2025-04-01T10:00:00Z] ERROR failed\njava.lang.IllegalStateException: boom\n\tat com.example.App.run(App.java:10)\n[2025-04-01T10:00:01Z] INFO ok\n",
+			entries:      2,
+			continuation: 2,
+			exceptions:   1,
+		},
+		{
+			name:         "junk line",
+			input:        "[2025-04-01T10:00:00Z] INFO started\nsome junk\n[2025-04-01T10:00:01Z] INFO ok\n",
+			entries:      2,
+			continuation: 1,
+			malformed:    1,
+			parseErrors:  []string{"line 2: continuation line is not part of a stack trace"},
+		},
+		{
+			name:         "junk lines of the last entry",
+			input:        "[2025-04-01T10:00:00Z] INFO started\nmissing [timestamp\n[2025-04-01T10:00:01Z] INFO ok\nmore junk\n  and more\n",
+			entries:      2,
+			continuation: 3,
+			malformed:    3,
+			parseErrors: []string{
+				"line 2: continuation line is not part of a stack trace",
+				"line 4: continuation line is not part of a stack trace",
+			},
+		},
+		{
+			name:         "blank lines",
+			input:        "[2025-04-01T10:00:00Z] INFO started\n\n  \n[2025-04-01T10:00:01Z] INFO ok\n",
+			entries:      2,
+			continuation: 2,
+		},
+		{
+			name:         "malformed entry and junk line",
+			input:        "[2025-04-01T10:00:00Z] INFO started\nINFO no timestamp\njunk\n[2025-04-01T10:00:01Z] INFO ok\n",
+			entries:      3,
+			continuation: 1,
+			malformed:    2,
+			parseErrors: []string{
+				"line 2: missing [timestamp]",
+				"line 3: continuation line is not part of a stack trace",
+			},
+		},
+		{
+			name:         "go panic",
+			input:        "[2025-04-01T10:00:00Z] INFO started\npanic: runtime error: index out of range [5] with length 3\n\ngoroutine 1 [running]:\nmain.main()\n\t/app/main.go:5 +0x1d\n[2025-04-01T10:00:01Z] INFO ok\n",
+			entries:      3,
+			continuation: 4,
+			exceptions:   1,
+		},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := scanString(t, p, FormatText, tt.input, nil)
+			if result.Entries != tt.entries || result.ContinuationLines != tt.continuation || result.MalformedLines != tt.malformed {
+				t.Errorf("got %d entries, %d continuation lines, %d malformed, want %d, %d, %d",
+					result.Entries, result.ContinuationLines, result.MalformedLines, tt.entries, tt.continuation, tt.malformed)
+			}
+			if strings.Join(result.ParseErrors, "\n") != strings.Join(tt.parseErrors, "\n") {
+				t.Errorf("got parse errors %q, want %q", result.ParseErrors, tt.parseErrors)
+			}
+			if len(result.Exceptions) != tt.exceptions {
+				t.Errorf("got %d exceptions, want %d", len(result.Exceptions), tt.exceptions)
+			}
+
+			// Chunks split at any line give the same result
+			want := resultsJSON(t, []models.Result{result})
+			for _, split := range lineEnds(tt.input) {
+				if got := resultsJSON(t, []models.Result{scanString(t, p, FormatText, tt.input, nil, split)}); got != want {
+					t.Errorf("split after offset %d:\n%s\nwant\n%s", split, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	input := `[2025-04-01T10:00:00Z] INFO login {"userId":"u1","status":200}
+[2025-04-01T10:01:00Z] ERROR payment failed {"userId":"u2","status":500}
+java.lang.IllegalStateException: boom
+	at com.example.Pay.run(Pay.java:10)
+[2025-04-01T10:02:00Z] WARN slow request {"userId":"u1","status":200}
+[2025-04-01T10:03:00Z] ERROR payment failed {"userId":"u1","status":502}
+[2025-04-01T10:04:00Z] DEBUG cache miss {"userId":"u3"}
+`
+	from := time.Date(2025, 4, 1, 10, 1, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 1, 10, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		filters    models.Filters
+		entries    int
+		levels     map[string]int
+		exceptions int
+		excluded   map[string]int
+	}{
+		{
+			name:       "none",
+			entries:    5,
+			levels:     map[string]int{LevelInfo: 1, LevelError: 2, LevelWarn: 1, LevelDebug: 1},
+			exceptions: 1,
+		},
+		{
+			name:       "time range",
+			filters:    models.Filters{From: &from, To: &to},
+			entries:    3,
+			levels:     map[string]int{LevelError: 2, LevelWarn: 1},
+			exceptions: 1,
+			excluded:   map[string]int{models.FilterTime: 2},
+		},
+		{
+			name:       "level",
+			filters:    models.Filters{Levels: []string{"error", "fatal"}},
+			entries:    2,
+			levels:     map[string]int{LevelError: 2},
+			exceptions: 1,
+			excluded:   map[string]int{models.FilterLevel: 3},
+		},
+		{
+			name:       "message",
+			filters:    models.Filters{Message: "^payment"},
+			entries:    2,
+			levels:     map[string]int{LevelError: 2},
+			exceptions: 1,
+			excluded:   map[string]int{models.FilterMessage: 3},
+		},
+		{
+			// The continuation lines are excluded with their entry
+			name:     "fields",
+			filters:  models.Filters{Fields: map[string]any{"userId": "u1", "status": float64(200)}},
+			entries:  2,
+			levels:   map[string]int{LevelInfo: 1, LevelWarn: 1},
+			excluded: map[string]int{models.FilterFields: 5},
+		},
+		{
+			// Entries count against the first filter they fail
+			name: "combined",
+			filters: models.Filters{
+				From:    &from,
+				Levels:  []string{"ERROR", "WARN"},
+				Message: "failed|slow",
+				Fields:  map[string]any{"userId": "u1"},
+			},
+			entries:  2,
+			levels:   map[string]int{LevelWarn: 1, LevelError: 1},
+			excluded: map[string]int{models.FilterTime: 1, models.FilterLevel: 1, models.FilterFields: 3},
+		},
+	}
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newEntryFilter(tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := scanString(t, p, FormatText, input, filter)
+			if result.TotalLines != 7 || result.Entries != tt.entries || len(result.Exceptions) != tt.exceptions {
+				t.Errorf("got %d lines, %d entries and %d exceptions, want 7, %d and %d",
+					result.TotalLines, result.Entries, len(result.Exceptions), tt.entries, tt.exceptions)
+			}
+			if !reflect.DeepEqual(result.Levels, tt.levels) {
+				t.Errorf("levels = %v, want %v", result.Levels, tt.levels)
+			}
+			if !reflect.DeepEqual(result.Excluded, tt.excluded) {
+				t.Errorf("excluded = %v, want %v", result.Excluded, tt.excluded)
+			}
+
+			// Chunks split inside the excluded entry exclude its lines too
+			for _, end := range lineEnds(input) {
+				split := scanString(t, p, FormatText, input, filter, end)
+				if !reflect.DeepEqual(split.Excluded, result.Excluded) || split.Entries != result.Entries {
+					t.Errorf("split at %d: got %d entries, excluded %v", end, split.Entries, split.Excluded)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidFilters(t *testing.T) {
+	from := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		filters models.Filters
+	}{
+		{name: "empty time range", filters: models.Filters{From: &from, To: &from}},
+		{name: "unknown level", filters: models.Filters{Levels: []string{"loud"}}},
+		{name: "invalid message", filters: models.Filters{Message: "(payment"}},
+	}
+	for _, tt := range tests {
+		if _, err := newEntryFilter(tt.filters); !errors.Is(err, errInvalidFilter) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, errInvalidFilter)
+		}
+	}
+	if filter, err := newEntryFilter(models.Filters{}); filter != nil || err != nil {
+		t.Errorf("got filter %v, %v without filters, want none", filter, err)
+	}
+}
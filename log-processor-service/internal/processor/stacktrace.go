@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+const (
+	// maxStackFrames is the number of top frames kept of a stack trace
+	maxStackFrames = 5
+	// maxExceptions bounds the exception types and top frames tracked per file
+	maxExceptions = 1000
+	// maxExceptionMessage bounds the length of the example message kept
+	maxExceptionMessage = 200
+)
+
+var (
+	// javaException matches "java.lang.IllegalStateException: message", also
+	// at the end of a log line or after "Caused by:"
+	javaException = regexp.MustCompile(`(?:^|[\s:])((?:[a-zA-Z_$][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error|Throwable))(?::\s*(.*))?$`)
+	javaFrame     = regexp.MustCompile(`^\s+at\s+(\S+)`)
+
+	pythonFrame     = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+), in (\S+)`)
+	pythonException = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::\s*(.*))?$`)
+
+	goFile = regexp.MustCompile(`^\t(\S+:\d+)`)
+)
+
+// stackTrace is an exception parsed from the lines of a log entry
+type stackTrace struct {
+	Type    string
+	Message string
+	Frames  []string
+}
+
+// startsStackTrace reports whether a line starts a stack trace printed without
+// a timestamp of its own, such as a Go panic
+func startsStackTrace(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("panic: ")) || bytes.HasPrefix(line, []byte("fatal error: ")) ||
+		bytes.HasPrefix(line, []byte("Traceback (most recent call last):"))
+}
+
+// parseStackTrace recognises Python tracebacks, Go panics and Java style
+// stack traces in the lines of an entry
+func parseStackTrace(lines []string) (stackTrace, bool) {
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "Traceback (most recent call last):"):
+			return parsePythonTrace(lines[i+1:])
+		case strings.HasPrefix(line, "panic: "):
+			return parseGoPanic("panic", strings.TrimPrefix(line, "panic: "), lines[i+1:])
+		case strings.HasPrefix(line, "fatal error: "):
+			return parseGoPanic("fatal error", strings.TrimPrefix(line, "fatal error: "), lines[i+1:])
+		}
+	}
+	return parseJavaTrace(lines)
+}
+
+// parseJavaTrace takes the exception named right before the first "at" frame
+// and its frames, up to the exception that caused it
+func parseJavaTrace(lines []string) (stackTrace, bool) {
+	var trace stackTrace
+	for i, line := range lines {
+		if trace.Type != "" && strings.HasPrefix(line, "Caused by: ") {
+			break
+		}
+		match := javaFrame.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if trace.Type == "" {
+			if i == 0 {
+				return trace, false
+			}
+			exception := javaException.FindStringSubmatch(strings.TrimRight(lines[i-1], " \t\r"))
+			if exception == nil {
+				return trace, false
+			}
+			trace.Type, trace.Message = exception[1], exception[2]
+		}
+		if len(trace.Frames) == maxStackFrames {
+			break
+		}
+		trace.Frames = append(trace.Frames, match[1])
+	}
+	return trace, trace.Type != ""
+}
+
+// parsePythonTrace takes the exception from the last line of a traceback.
+// Frames are listed most recent call last, so the top frames are at the end.
+func parsePythonTrace(lines []string) (stackTrace, bool) {
+	var trace stackTrace
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimRight(lines[i], " \t\r")
+		if trace.Type == "" {
+			if match := pythonException.FindStringSubmatch(line); match != nil {
+				trace.Type, trace.Message = match[1], match[2]
+			}
+			continue
+		}
+		if match := pythonFrame.FindStringSubmatch(line); match != nil && len(trace.Frames) < maxStackFrames {
+			trace.Frames = append(trace.Frames, match[1]+":"+match[2]+" in "+match[3])
+		}
+	}
+	return trace, trace.Type != ""
+}
+
+// parseGoPanic takes the frames of the panicking goroutine, skipping those of
+// the runtime, so the top frame is where the panic was raised
+func parseGoPanic(kind, message string, lines []string) (stackTrace, bool) {
+	trace := stackTrace{Type: kind, Message: message}
+	if rest, ok := strings.CutPrefix(message, "runtime error: "); ok {
+		trace.Type, trace.Message = "runtime error", rest
+	}
+
+	// Frames are a function line followed by an indented file line
+	inGoroutine := false
+	for i := 0; i+1 < len(lines) && len(trace.Frames) < maxStackFrames; i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "goroutine ") {
+			if inGoroutine {
+				break
+			}
+			inGoroutine = true
+			continue
+		}
+		if !inGoroutine || line == "" || line[0] == '\t' {
+			continue
+		}
+
+		function := line
+		if end := strings.LastIndexByte(line, '('); end > 0 {
+			function = line[:end]
+		}
+		file := goFile.FindStringSubmatch(lines[i+1])
+		if file == nil || function == "panic" || strings.HasPrefix(function, "runtime.") {
+			continue
+		}
+		trace.Frames = append(trace.Frames, function+" ("+file[1]+")")
+	}
+	return trace, true
+}
+
+// exceptionKey identifies the traces counted together
+type exceptionKey struct {
+	typ   string
+	frame string
+}
+
+// exceptionAggregator counts stack traces by exception type and top frame
+type exceptionAggregator struct {
+	counts map[exceptionKey]*models.ExceptionStats
+}
+
+func newExceptionAggregator() *exceptionAggregator {
+	return &exceptionAggregator{counts: make(map[exceptionKey]*models.ExceptionStats)}
+}
+
+// Add counts a trace found in an entry of the given level
+func (a *exceptionAggregator) Add(trace stackTrace, level string) {
+	key := exceptionKey{typ: trace.Type}
+	if len(trace.Frames) > 0 {
+		key.frame = trace.Frames[0]
+	}
+
+	stats, ok := a.counts[key]
+	if !ok {
+		if len(a.counts) >= maxExceptions {
+			return
+		}
+		message := trace.Message
+		if len(message) > maxExceptionMessage {
+			message = message[:maxExceptionMessage]
+		}
+		stats = &models.ExceptionStats{
+			Type:     key.typ,
+			TopFrame: key.frame,
+			Levels:   make(map[string]int),
+			Message:  message,
+			Frames:   trace.Frames,
+		}
+		a.counts[key] = stats
+	}
+	stats.Count++
+	if level != "" {
+		stats.Levels[level]++
+	}
+}
+
+// Merge adds the counts of another aggregator
+func (a *exceptionAggregator) Merge(other *exceptionAggregator) {
+	for key, stats := range other.counts {
+		existing, ok := a.counts[key]
+		if !ok {
+			if len(a.counts) < maxExceptions {
+				a.counts[key] = stats
+			}
+			continue
+		}
+		existing.Count += stats.Count
+		for level, count := range stats.Levels {
+			existing.Levels[level] += count
+		}
+	}
+}
+
+// Stats returns the exceptions, most frequent first, including the trace of
+// an entry that is not complete yet without counting it for good
+func (a *exceptionAggregator) Stats(pending *stackTrace, level string) []models.ExceptionStats {
+	counts := a.counts
+	if pending != nil {
+		counts = make(map[exceptionKey]*models.ExceptionStats, len(a.counts)+1)
+		for key, stats := range a.counts {
+			copied := *stats
+			copied.Levels = make(map[string]int, len(stats.Levels))
+			for level, count := range stats.Levels {
+				copied.Levels[level] = count
+			}
+			counts[key] = &copied
+		}
+		(&exceptionAggregator{counts: counts}).Add(*pending, level)
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	stats := make([]models.ExceptionStats, 0, len(counts))
+	for _, exception := range counts {
+		stats = append(stats, *exception)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		if stats[i].Type != stats[j].Type {
+			return stats[i].Type < stats[j].Type
+		}
+		return stats[i].TopFrame < stats[j].TopFrame
+	})
+	return stats
+}
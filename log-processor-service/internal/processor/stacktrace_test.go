@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStackTrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  stackTrace
+		ok    bool
+	}{
+		{
+			name: "java",
+			input: "ERROR request failed\n" +
+				"java.lang.IllegalStateException: boom\n" +
+				"\tat com.example.App.run(App.java:10)\n" +
+				"\tat com.example.Main.main(Main.java:3)",
+			want: stackTrace{
+				Type:    "java.lang.IllegalStateException",
+				Message: "boom",
+				Frames:  []string{"com.example.App.run(App.java:10)", "com.example.Main.main(Main.java:3)"},
+			},
+			ok: true,
+		},
+		{
+			name: "java caused by",
+			input: "ERROR request failed: java.lang.RuntimeException: wrapped\n" +
+				"\tat com.example.Service.call(Service.java:42)\n" +
+				"\tat com.example.App.run(App.java:10)\n" +
+				"Caused by: java.io.IOException: disk full\n" +
+				"\tat java.io.FileOutputStream.write(FileOutputStream.java:326)\n" +
+				"\t... 2 more",
+			want: stackTrace{
+				Type:    "java.lang.RuntimeException",
+				Message: "wrapped",
+				Frames:  []string{"com.example.Service.call(Service.java:42)", "com.example.App.run(App.java:10)"},
+			},
+			ok: true,
+		},
+		{
+			name: "go panic",
+			input: "panic: runtime error: index out of range [5] with length 3\n" +
+				"\n" +
+				"goroutine 1 [running]:\n" +
+				"panic({0x4a1c20, 0xc000012345})\n" +
+				"\t/usr/local/go/src/runtime/panic.go:770 +0x132\n" +
+				"main.lookup(...)\n" +
+				"\t/app/main.go:12\n" +
+				"main.main()\n" +
+				"\t/app/main.go:5 +0x1d\n" +
+				"\n" +
+				"goroutine 6 [chan receive]:\n" +
+				"main.worker()\n" +
+				"\t/app/worker.go:20 +0x40",
+			want: stackTrace{
+				Type:    "runtime error",
+				Message: "index out of range [5] with length 3",
+				Frames:  []string{"main.lookup (/app/main.go:12)", "main.main (/app/main.go:5)"},
+			},
+			ok: true,
+		},
+		{
+			name: "go fatal error",
+			input: "fatal error: all goroutines are asleep - deadlock!\n" +
+				"\n" +
+				"goroutine 1 [chan receive]:\n" +
+				"main.main()\n" +
+				"\t/app/main.go:8 +0x2a",
+			want: stackTrace{
+				Type:    "fatal error",
+				Message: "all goroutines are asleep - deadlock!",
+				Frames:  []string{"main.main (/app/main.go:8)"},
+			},
+			ok: true,
+		},
+		{
+			name: "python traceback",
+			input: "ERROR handler crashed\n" +
+				"Traceback (most recent call last):\n" +
+				"  File \"/app/server.py\", line 40, in handle\n" +
+				"    result = process(request)\n" +
+				"  File \"/app/worker.py\", line 12, in process\n" +
+				"    return items[key]\n" +
+				"KeyError: 'user'",
+			want: stackTrace{
+				Type:    "KeyError",
+				Message: "'user'",
+				Frames:  []string{"/app/worker.py:12 in process", "/app/server.py:40 in handle"},
+			},
+			ok: true,
+		},
+		{
+			name:  "not a trace",
+			input: "INFO started\nsome text\n  indented text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseStackTrace(strings.Split(tt.input, "\n"))
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trace = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// syslogFacilities are the names of the syslog facilities by code
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// bsdTimeLayout is the timestamp of RFC 3164, which has no year
+const bsdTimeLayout = "Jan _2 15:04:05"
+
+var errInvalidSyslog = errors.New("not a syslog line")
+
+// syslogEntryStart matches the priority or timestamp starting a syslog line
+var syslogEntryStart = regexp.MustCompile(`^(<\d{1,3}>|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} |\d{4}-\d{2}-\d{2}T\d{2}:)`)
+
+// syslogParser parses syslog lines in the format of RFC 5424:
+//
+//	<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [id@1 key="value"] message
+//
+// and of RFC 3164, also written without priority by local syslog daemons:
+//
+//	<34>Oct 11 22:14:15 host su[1234]: message
+//
+// The level follows the severity of the priority; lines without one are left
+// to the classifier. Lines with neither a priority nor a RFC 3164 timestamp
+// need a tag, so other logs starting with a RFC 3339 timestamp are not taken
+// for syslog. RFC 3164 timestamps, which have no year, are taken to be of the
+// current year unless that puts them in the future.
+type syslogParser struct{}
+
+func (syslogParser) Name() string { return FormatSyslog }
+
+func (syslogParser) EntryStart() *regexp.Regexp { return syslogEntryStart }
+
+func (syslogParser) Parse(line []byte) (models.LogEntry, error) {
+	var entry models.LogEntry
+	line = bytes.TrimRight(line, "\r")
+	payload := make(map[string]any)
+
+	rest := line
+	priority := len(rest) > 0 && rest[0] == '<'
+	if priority {
+		end := bytes.IndexByte(rest, '>')
+		if end < 0 {
+			return entry, fmt.Errorf("%w: unterminated priority", errInvalidSyslog)
+		}
+		priority, err := strconv.Atoi(string(rest[1:end]))
+		if err != nil || priority < 0 || priority >= len(syslogFacilities)*8 {
+			return entry, fmt.Errorf("%w: invalid priority %q", errInvalidSyslog, rest[1:end])
+		}
+		entry.Level = syslogSeverities[priority%8]
+		payload["facility"] = syslogFacilities[priority/8]
+		rest = rest[end+1:]
+	}
+
+	var err error
+	if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && rest[1] == ' ' {
+		err = parseRFC5424(rest[2:], &entry, payload)
+	} else {
+		err = parseRFC3164(rest, priority, &entry, payload)
+	}
+	if len(payload) > 0 {
+		entry.Payload = payload
+	}
+	return entry, err
+}
+
+// parseRFC5424 parses the part of a RFC 5424 line after the version
+func parseRFC5424(rest []byte, entry *models.LogEntry, payload map[string]any) error {
+	var header [5][]byte
+	for i := range header {
+		var ok bool
+		if header[i], rest, ok = nextToken(rest); !ok {
+			return fmt.Errorf("%w: incomplete header", errInvalidSyslog)
+		}
+	}
+
+	if timestamp := header[0]; string(timestamp) != "-" {
+		t, err := parseTimestampValue(string(timestamp))
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %v", err)
+		}
+		entry.Timestamp = t
+	}
+	for i, key := range []string{"host", "app", "pid", "msgid"} {
+		if value := header[i+1]; string(value) != "-" {
+			payload[key] = string(value)
+		}
+	}
+
+	rest = bytes.TrimLeft(rest, " ")
+	if len(rest) > 0 && rest[0] == '[' {
+		data, after, err := parseStructuredData(rest)
+		if err != nil {
+			return err
+		}
+		payload["sd"] = data
+		rest = after
+	} else if len(rest) > 0 && rest[0] == '-' {
+		rest = rest[1:]
+	}
+	entry.Message = string(bytes.TrimPrefix(bytes.TrimLeft(rest, " "), bom))
+	return nil
+}
+
+// parseStructuredData parses the structured data elements of a RFC 5424 line,
+// returning their parameters by element id and the rest of the line
+func parseStructuredData(b []byte) (map[string]any, []byte, error) {
+	data := make(map[string]any)
+	for len(b) > 0 && b[0] == '[' {
+		end := bytes.IndexAny(b, " ]")
+		if end < 0 {
+			return nil, nil, fmt.Errorf("%w: unterminated structured data", errInvalidSyslog)
+		}
+		params := make(map[string]any)
+		data[string(b[1:end])] = params
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			eq := bytes.IndexByte(b, '=')
+			if eq < 0 || eq+1 >= len(b) || b[eq+1] != '"' {
+				return nil, nil, fmt.Errorf("%w: invalid structured data parameter", errInvalidSyslog)
+			}
+			name := string(bytes.TrimLeft(b[:eq], " "))
+			value, n, ok := unquoteSyslog(b[eq+1:])
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: unterminated structured data value", errInvalidSyslog)
+			}
+			params[name] = value
+			b = b[eq+1+n:]
+		}
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, fmt.Errorf("%w: unterminated structured data", errInvalidSyslog)
+		}
+		b = b[1:]
+	}
+	return data, b, nil
+}
+
+// unquoteSyslog reads a quoted parameter value, in which '"', '\' and ']' are
+// escaped by a backslash, returning it and the number of bytes it took
+func unquoteSyslog(b []byte) (string, int, bool) {
+	var value []byte
+	for i := 1; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']'):
+			i++
+			value = append(value, b[i])
+		case c == '"':
+			return string(value), i + 1, true
+		default:
+			value = append(value, c)
+		}
+	}
+	return "", 0, false
+}
+
+// parseRFC3164 parses a RFC 3164 line after the priority, if it has one. Its
+// timestamp may also be in RFC 3339, as written by rsyslog.
+func parseRFC3164(rest []byte, priority bool, entry *models.LogEntry, payload map[string]any) error {
+	rest = bytes.TrimLeft(rest, " ")
+	needsTag := false
+	if len(rest) >= len(bsdTimeLayout) && rest[3] == ' ' {
+		t, err := time.Parse(bsdTimeLayout, string(rest[:len(bsdTimeLayout)]))
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %v", err)
+		}
+		entry.Timestamp = withYear(t, time.Now())
+		rest = rest[len(bsdTimeLayout):]
+	} else {
+		token, after, _ := nextToken(rest)
+		t, err := parseTimestampValue(string(token))
+		if err != nil {
+			return fmt.Errorf("%w: expected a priority or timestamp", errInvalidSyslog)
+		}
+		entry.Timestamp = t
+		rest = after
+		needsTag = !priority
+	}
+
+	host, rest, ok := nextToken(rest)
+	if !ok {
+		return fmt.Errorf("%w: missing host", errInvalidSyslog)
+	}
+	payload["host"] = string(host)
+
+	// The tag is the program name, with its process id in brackets, ended by
+	// a colon. Lines without one are all message.
+	message := bytes.TrimLeft(rest, " ")
+	if tag, after, ok := nextToken(message); ok && len(tag) > 1 && tag[len(tag)-1] == ':' {
+		tag = tag[:len(tag)-1]
+		if open := bytes.IndexByte(tag, '['); open > 0 && tag[len(tag)-1] == ']' {
+			payload["pid"] = string(tag[open+1 : len(tag)-1])
+			tag = tag[:open]
+		}
+		payload["app"] = string(tag)
+		message = after
+	} else if needsTag {
+		return fmt.Errorf("%w: missing tag", errInvalidSyslog)
+	}
+	entry.Message = string(message)
+	return nil
+}
+
+// withYear sets the year of a timestamp without one to the latest that does
+// not put it more than a day after now
+func withYear(t, now time.Time) time.Time {
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
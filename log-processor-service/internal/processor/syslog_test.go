@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/config"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestSyslogParser(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		level     string
+		timestamp string // RFC 3339, or the RFC 3164 layout without a year
+		message   string
+		payload   map[string]any
+	}{
+		{
+			name:      "RFC 5424",
+			line:      `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`,
+			level:     LevelInfo,
+			timestamp: "2003-10-11T22:14:15.003Z",
+			message:   "An application event",
+			payload: map[string]any{
+				"facility": "local4",
+				"host":     "mymachine.example.com",
+				"app":      "evntslog",
+				"msgid":    "ID47",
+				"sd": map[string]any{
+					"exampleSDID@32473": map[string]any{"iut": "3", "eventSource": "Application"},
+				},
+			},
+		},
+		{
+			name:      "RFC 5424 without structured data",
+			line:      "<11>1 2003-10-11T22:14:15Z host app 1234 - - disk \"sda\" failed\r",
+			level:     LevelError,
+			timestamp: "2003-10-11T22:14:15Z",
+			message:   `disk "sda" failed`,
+			payload:   map[string]any{"facility": "user", "host": "host", "app": "app", "pid": "1234"},
+		},
+		{
+			name:      "RFC 3164",
+			line:      "<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8",
+			level:     LevelFatal,
+			timestamp: "Oct 11 22:14:15",
+			message:   "'su root' failed for lonvick on /dev/pts/8",
+			payload:   map[string]any{"facility": "auth", "host": "mymachine", "app": "su", "pid": "230"},
+		},
+		{
+			name:      "RFC 3164 without priority or tag",
+			line:      "Feb  5 07:00:01 web01 started nightly backup",
+			timestamp: "Feb  5 07:00:01",
+			message:   "started nightly backup",
+			payload:   map[string]any{"host": "web01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := syslogParser{}.Parse([]byte(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Level != tt.level || entry.Message != tt.message {
+				t.Errorf("got level %q, message %q, want %q, %q", entry.Level, entry.Message, tt.level, tt.message)
+			}
+			if got := entry.Timestamp.Format(bsdTimeLayout); len(tt.timestamp) == len(bsdTimeLayout) && got != tt.timestamp {
+				t.Errorf("got timestamp %s, want %s", got, tt.timestamp)
+			}
+			if want, err := time.Parse(time.RFC3339, tt.timestamp); err == nil && !entry.Timestamp.Equal(want) {
+				t.Errorf("got timestamp %v, want %v", entry.Timestamp, want)
+			}
+			if !reflect.DeepEqual(entry.Payload, tt.payload) {
+				t.Errorf("got payload %v, want %v", entry.Payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestSyslogParserRejects(t *testing.T) {
+	for _, line := range []string{
+		"<999>Oct 11 22:14:15 host app: message",
+		"<34 Oct 11 22:14:15 host app: message",
+		"<165>1 2003-10-11T22:14:15Z host",
+		`<165>1 2003-10-11T22:14:15Z host app - - [id key="value] message`,
+		"just some text",
+		"2025-04-01T10:00:00Z ERROR something happened",
+	} {
+		if _, err := (syslogParser{}).Parse([]byte(line)); !errors.Is(err, errInvalidSyslog) {
+			t.Errorf("Parse(%q) = %v, want a syslog error", line, err)
+		}
+	}
+}
+
+func TestWithYear(t *testing.T) {
+	now := time.Date(2025, time.January, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		month time.Month
+		day   int
+		year  int
+	}{
+		{time.January, 1, 2025},
+		{time.January, 3, 2025},
+		{time.December, 31, 2024},
+	}
+	for _, tt := range tests {
+		got := withYear(time.Date(0, tt.month, tt.day, 0, 0, 0, 0, time.UTC), now)
+		if got.Year() != tt.year {
+			t.Errorf("withYear(%s %d) is in %d, want %d", tt.month, tt.day, got.Year(), tt.year)
+		}
+	}
+}
+
+func TestSyslogStackTrace(t *testing.T) {
+	input := "<34>Oct 11 22:14:15 host su: lookup failed\n" +
+		"java.lang.NullPointerException: x\n" +
+		"\tat com.a.B.c(B.java:1)\n" +
+		"\tat com.a.B.main(B.java:9)\n" +
+		"<165>1 2003-10-11T22:14:15.003Z host app - - - next entry\n"
+
+	p, err := NewProcessor(config.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := p.newFormatState(FormatSyslog, models.ColumnMapping{}, nil)
+	state, err = p.scanLines(context.Background(), strings.NewReader(input), state, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := state.finish("syslog.log", 10, 10)
+	if result.Entries != 2 || result.ContinuationLines != 3 || result.MalformedLines != 0 {
+		t.Errorf("got %d entries, %d continuation lines, %d malformed, want 2, 3, 0: %v", result.Entries, result.ContinuationLines, result.MalformedLines, result.ParseErrors)
+	}
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Type != "java.lang.NullPointerException" || result.Exceptions[0].TopFrame != "com.a.B.c(B.java:1)" {
+		t.Fatalf("got exceptions %+v, want a java.lang.NullPointerException at com.a.B.c(B.java:1)", result.Exceptions)
+	}
+	if result.Exceptions[0].Levels[LevelFatal] != 1 {
+		t.Errorf("got exception levels %v, want the level of its entry", result.Exceptions[0].Levels)
+	}
+}
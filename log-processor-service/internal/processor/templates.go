@@ -0,0 +1,396 @@
+package processor
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+const (
+	// templateSimilarity is the share of equal tokens needed to join an existing template
+	templateSimilarity = 0.5
+	// maxTemplates bounds the number of templates kept per file
+	maxTemplates = 10000
+	// maxMaskedTexts bounds the cache of masked message texts
+	maxMaskedTexts = 4096
+
+	wildcard = "<*>"
+	// jsonMask stands for the JSON payload of a message
+	jsonMask = " <JSON>"
+)
+
+// templateCluster is a group of lines sharing one template
+type templateCluster struct {
+	level   string
+	tokens  []string
+	count   int
+	example string
+}
+
+// groupKey identifies the templates a line can join: same level, token count and first token
+type groupKey struct {
+	level string
+	shape string
+}
+
+// maskedText is the masked form of a message text
+type maskedText struct {
+	tokens []string
+	shape  string
+}
+
+// templateMiner groups lines into templates in the spirit of Drain: variables are
+// masked, lines are bucketed by level, token count and first token, and joined
+// with the most similar template in the bucket, turning differing tokens into <*>
+type templateMiner struct {
+	groups   map[groupKey][]*templateCluster
+	clusters int
+
+	// masked caches recently masked texts, as most lines repeat a handful of
+	// messages, text holds the text of the line being added
+	masked map[string]maskedText
+	text   []byte
+}
+
+func newTemplateMiner() *templateMiner {
+	return &templateMiner{
+		groups: make(map[groupKey][]*templateCluster),
+		masked: make(map[string]maskedText),
+	}
+}
+
+// Add assigns a line to a template, using the message of the parsed entry if
+// there is one; the line is kept as example of new templates
+func (m *templateMiner) Add(level string, entry *models.LogEntry, line []byte) {
+	switch {
+	case entry == nil:
+		m.text = append(m.text[:0], bytes.TrimSpace(line)...)
+	default:
+		m.text = append(m.text[:0], entry.Message...)
+		if entry.Payload != nil {
+			m.text = append(m.text, jsonMask...)
+		}
+	}
+	m.add(level, line)
+}
+
+// AddMessage is Add for an entry of the generator's format, whose message and
+// whether it has a payload are read from its line
+func (m *templateMiner) AddMessage(level string, message []byte, payload bool, line []byte) {
+	m.text = append(m.text[:0], message...)
+	if payload {
+		m.text = append(m.text, jsonMask...)
+	}
+	m.add(level, line)
+}
+
+// add assigns a line to the template of the text in m.text
+func (m *templateMiner) add(level string, line []byte) {
+	text := m.mask(m.text)
+	if len(text.tokens) == 0 {
+		return
+	}
+
+	key := groupKey{level: level, shape: text.shape}
+	if cluster := m.match(key, text.tokens); cluster != nil {
+		cluster.count++
+		return
+	}
+	m.insert(key, &templateCluster{
+		level:   level,
+		tokens:  append([]string(nil), text.tokens...),
+		count:   1,
+		example: string(line),
+	})
+}
+
+// mask returns the masked tokens of a text, using the cache when possible
+func (m *templateMiner) mask(text []byte) maskedText {
+	if cached, ok := m.masked[string(text)]; ok {
+		return cached
+	}
+
+	key := string(text)
+	var masked maskedText
+	masked.tokens = maskTokens(key)
+	if len(masked.tokens) > 0 {
+		masked.shape = strconv.Itoa(len(masked.tokens)) + "|" + masked.tokens[0]
+	}
+
+	if len(m.masked) >= maxMaskedTexts {
+		clear(m.masked)
+	}
+	m.masked[key] = masked
+	return masked
+}
+
+// Merge folds the templates of another miner into this one
+func (m *templateMiner) Merge(other *templateMiner) {
+	keys := make([]groupKey, 0, len(other.groups))
+	for key := range other.groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].shape < keys[j].shape
+	})
+
+	for _, key := range keys {
+		for _, cluster := range other.groups[key] {
+			if existing := m.match(key, cluster.tokens); existing != nil {
+				existing.count += cluster.count
+				continue
+			}
+			m.insert(key, cluster)
+		}
+	}
+}
+
+// match finds the most similar template of a group and generalises it to cover tokens
+func (m *templateMiner) match(key groupKey, tokens []string) *templateCluster {
+	var best *templateCluster
+	bestScore := 0.0
+	for _, cluster := range m.groups[key] {
+		if score := similarity(cluster.tokens, tokens); score > bestScore {
+			best, bestScore = cluster, score
+		}
+	}
+	if best == nil || bestScore < templateSimilarity {
+		return nil
+	}
+
+	for i, token := range tokens {
+		if best.tokens[i] != token {
+			best.tokens[i] = wildcard
+		}
+	}
+	return best
+}
+
+func (m *templateMiner) insert(key groupKey, cluster *templateCluster) {
+	if m.clusters >= maxTemplates {
+		return
+	}
+	m.clusters++
+	m.groups[key] = append(m.groups[key], cluster)
+}
+
+// clusterSnapshot is the serialisable form of a templateCluster
+type clusterSnapshot struct {
+	Level   string   `json:"level"`
+	Shape   string   `json:"shape"`
+	Tokens  []string `json:"tokens"`
+	Count   int      `json:"count"`
+	Example string   `json:"example"`
+}
+
+// snapshot returns the templates of the miner, keeping the order of the
+// templates within a group as it decides which template a line joins
+func (m *templateMiner) snapshot() []clusterSnapshot {
+	keys := make([]groupKey, 0, len(m.groups))
+	for key := range m.groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].shape < keys[j].shape
+	})
+
+	snapshot := make([]clusterSnapshot, 0, m.clusters)
+	for _, key := range keys {
+		for _, cluster := range m.groups[key] {
+			snapshot = append(snapshot, clusterSnapshot{
+				Level:   cluster.level,
+				Shape:   key.shape,
+				Tokens:  append([]string(nil), cluster.tokens...),
+				Count:   cluster.count,
+				Example: cluster.example,
+			})
+		}
+	}
+	return snapshot
+}
+
+// restore adds the templates of a snapshot to an empty miner
+func (m *templateMiner) restore(snapshot []clusterSnapshot) {
+	for _, cluster := range snapshot {
+		m.insert(groupKey{level: cluster.Level, shape: cluster.Shape}, &templateCluster{
+			level:   cluster.Level,
+			tokens:  cluster.Tokens,
+			count:   cluster.Count,
+			example: cluster.Example,
+		})
+	}
+}
+
+// Top returns the topN templates of each level, most frequent first
+func (m *templateMiner) Top(topN int) []models.Template {
+	byLevel := make(map[string][]*templateCluster)
+	for _, clusters := range m.groups {
+		for _, cluster := range clusters {
+			byLevel[cluster.level] = append(byLevel[cluster.level], cluster)
+		}
+	}
+
+	levels := make([]string, 0, len(byLevel))
+	for level := range byLevel {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	var templates []models.Template
+	for _, level := range levels {
+		clusters := byLevel[level]
+		ranked := make([]models.Template, len(clusters))
+		for i, cluster := range clusters {
+			ranked[i] = models.Template{
+				Level:    level,
+				Template: strings.Join(cluster.tokens, " "),
+				Count:    cluster.count,
+				Example:  cluster.example,
+			}
+		}
+		// Clusters come in no particular order, ties are broken by their
+		// text so the same lines always give the same templates
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Count != ranked[j].Count {
+				return ranked[i].Count > ranked[j].Count
+			}
+			if ranked[i].Template != ranked[j].Template {
+				return ranked[i].Template < ranked[j].Template
+			}
+			return ranked[i].Example < ranked[j].Example
+		})
+		templates = append(templates, ranked[:min(topN, len(ranked))]...)
+	}
+	return templates
+}
+
+// similarity is the share of positions where both token lists agree
+func similarity(template, tokens []string) float64 {
+	equal := 0
+	for i, token := range tokens {
+		if template[i] == token || template[i] == wildcard {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(tokens))
+}
+
+// maskTokens splits text into tokens and replaces variable parts such as
+// numbers, IPs, UUIDs and JSON blobs with placeholders
+func maskTokens(text string) []string {
+	text = maskJSON(text)
+
+	fields := strings.Fields(text)
+	for i, field := range fields {
+		fields[i] = maskToken(field)
+	}
+	return fields
+}
+
+// maskJSON replaces balanced {...} blocks with <JSON>
+func maskJSON(text string) string {
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return text
+	}
+
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '{':
+			if depth == 0 {
+				b.WriteString(" <JSON> ")
+			}
+			depth++
+		case text[i] == '}' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+func maskToken(token string) string {
+	core := strings.Trim(token, ",;:()[]\"'")
+	if core == "" {
+		return token
+	}
+
+	switch {
+	case isUUID(core):
+		return strings.Replace(token, core, "<UUID>", 1)
+	case isIP(core):
+		return strings.Replace(token, core, "<IP>", 1)
+	case isNumber(core):
+		return strings.Replace(token, core, "<NUM>", 1)
+	case strings.ContainsAny(core, "0123456789"):
+		return wildcard
+	}
+	return token
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isIP(s string) bool {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return strings.ContainsAny(s, ".:") && net.ParseIP(s) != nil
+}
+
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if strings.HasPrefix(s, "0x") && len(s) > 2 {
+		for i := 2; i < len(s); i++ {
+			if !isHex(s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	digits := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			digits++
+		case s[i] == '.':
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func isHex(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+func TestTemplatesTiesAreOrderedByText(t *testing.T) {
+	messages := []string{"disk full", "cache miss on read", "user logged in", "connection reset", "job done"}
+	var want []models.Template
+	for round := range 20 {
+		m := newTemplateMiner()
+		for i := range messages {
+			message := messages[(i+round)%len(messages)]
+			m.Add(LevelInfo, &models.LogEntry{Message: message}, []byte(message))
+		}
+		m.Add(LevelInfo, &models.LogEntry{Message: "job done"}, []byte("job done"))
+
+		got := m.Top(10)
+		if want == nil {
+			want = got
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got templates %v, want %v", got, want)
+		}
+	}
+	var texts []string
+	for _, template := range want {
+		texts = append(texts, template.Template)
+	}
+	if wantTexts := []string{"job done", "cache miss on read", "connection reset", "disk full", "user logged in"}; !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("got templates %q, want %q", texts, wantTexts)
+	}
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+)
+
+// timelineBuilder counts lines per level in fixed size time buckets
+type timelineBuilder struct {
+	bucketSize time.Duration
+	buckets    map[int64]map[string]int // keyed by bucket start in unix seconds
+}
+
+func newTimelineBuilder(bucketSize time.Duration) *timelineBuilder {
+	return &timelineBuilder{
+		bucketSize: bucketSize,
+		buckets:    make(map[int64]map[string]int),
+	}
+}
+
+// Add counts a line of the given level at time t
+func (b *timelineBuilder) Add(t time.Time, level string) {
+	key := t.Truncate(b.bucketSize).Unix()
+	counts, ok := b.buckets[key]
+	if !ok {
+		counts = make(map[string]int)
+		b.buckets[key] = counts
+	}
+	counts[level]++
+}
+
+// Merge adds the counts of another builder with the same bucket size
+func (b *timelineBuilder) Merge(other *timelineBuilder) {
+	for key, counts := range other.buckets {
+		existing, ok := b.buckets[key]
+		if !ok {
+			b.buckets[key] = counts
+			continue
+		}
+		for level, count := range counts {
+			existing[level] += count
+		}
+	}
+}
+
+// Timeline returns the buckets in chronological order
+func (b *timelineBuilder) Timeline() *models.Timeline {
+	if len(b.buckets) == 0 {
+		return nil
+	}
+
+	keys := make([]int64, 0, len(b.buckets))
+	for key := range b.buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	timeline := &models.Timeline{
+		BucketSize: b.bucketSize.String(),
+		Buckets:    make([]models.TimelineBucket, len(keys)),
+	}
+	for i, key := range keys {
+		timeline.Buckets[i] = models.TimelineBucket{
+			Start:  time.Unix(key, 0).UTC(),
+			Counts: b.buckets[key],
+		}
+	}
+	return timeline
+}
+
+// snapshot returns the counts of the builder, keyed by bucket start in unix seconds
+func (b *timelineBuilder) snapshot() map[int64]map[string]int {
+	return b.buckets
+}
+
+// restore replaces the counts of the builder with those of a snapshot
+func (b *timelineBuilder) restore(buckets map[int64]map[string]int) {
+	if buckets != nil {
+		b.buckets = buckets
+	}
+}
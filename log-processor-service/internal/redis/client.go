@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -14,8 +15,50 @@ type Message struct {
 }
 
 type ProcessingMessage struct {
+	JobID     string   `json:"job_id,omitempty"`
 	FileNames []string `json:"file_names"`
 	ClientID  string   `json:"client_id"`
+
+	// Format is the format of all files, told by their extension if empty
+	Format string `json:"format,omitempty"`
+
+	// Columns maps the columns of CSV files, detected from their header if nil
+	Columns *models.ColumnMapping `json:"columns,omitempty"`
+
+	// Filters select the entries analysed, all are if nil
+	Filters *models.Filters `json:"filters,omitempty"`
+
+	// Index builds the full-text search index of the files
+	Index bool `json:"index,omitempty"`
+}
+
+// Actions of a FollowMessage
+const (
+	FollowStart = "start"
+	FollowStop  = "stop"
+)
+
+// FollowMessage starts or stops following a file that is still being written
+type FollowMessage struct {
+	Action   string `json:"action"`
+	FileName string `json:"file_name"`
+	ClientID string `json:"client_id"`
+	// FromEnd skips the lines already in the file when following starts
+	FromEnd bool `json:"from_end,omitempty"`
+}
+
+// DetectMessage asks for the format of an uploaded file, which is published
+// as a DetectReply on ReplyChannel
+type DetectMessage struct {
+	FileName     string `json:"file_name"`
+	ReplyChannel string `json:"reply_channel"`
+}
+
+// DetectReply is the detected format of a file, or the error detecting it
+type DetectReply struct {
+	Detection     *models.Detection    `json:"detection,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	ErrorCategory models.ErrorCategory `json:"error_category,omitempty"`
 }
 
 type ProgressMessage struct {
@@ -59,20 +102,53 @@ func (c *Client) Subscribe(channel string) (*redis.PubSub, error) {
 func (c *Client) Publish(channel string, message interface{}) error {
 	ctx := context.Background()
 
-	// Convert message to JSON if it's not already a string
-	var messageStr string
+	messageStr, err := encode(message)
+	if err != nil {
+		return err
+	}
+
+	return c.client.Publish(ctx, channel, messageStr).Err()
+}
+
+// HSet stores a value in a field of a hash, encoded like published messages
+func (c *Client) HSet(key, field string, value interface{}) error {
+	ctx := context.Background()
+
+	valueStr, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	return c.client.HSet(ctx, key, field, valueStr).Err()
+}
+
+// HGetAll returns the fields of a hash, which is empty if the key does not exist
+func (c *Client) HGetAll(key string) (map[string]string, error) {
+	return c.client.HGetAll(context.Background(), key).Result()
+}
+
+// HDel removes fields from a hash
+func (c *Client) HDel(key string, fields ...string) error {
+	return c.client.HDel(context.Background(), key, fields...).Err()
+}
+
+// Del removes keys
+func (c *Client) Del(keys ...string) error {
+	return c.client.Del(context.Background(), keys...).Err()
+}
+
+// encode converts a message to JSON if it's not already a string
+func encode(message interface{}) (string, error) {
 	switch v := message.(type) {
 	case string:
-		messageStr = v
+		return v, nil
 	default:
 		jsonBytes, err := json.Marshal(v)
 		if err != nil {
-			return fmt.Errorf("failed to marshal message: %v", err)
+			return "", fmt.Errorf("failed to marshal message: %v", err)
 		}
-		messageStr = string(jsonBytes)
+		return string(jsonBytes), nil
 	}
-
-	return c.client.Publish(ctx, channel, messageStr).Err()
 }
 
 func (c *Client) Close() error {
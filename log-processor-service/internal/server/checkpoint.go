@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/processor"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/redis"
+)
+
+const (
+	// jobsKey is the hash of the processing messages of unfinished jobs, by job ID
+	jobsKey = "processing_jobs"
+	// checkpointsKeyPrefix followed by the job ID is the hash of the
+	// checkpoints of a job, by file name and chunk
+	checkpointsKeyPrefix = "processing_checkpoints:"
+)
+
+// newJobID creates an ID for jobs the backend did not assign one to
+func newJobID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// startJob records a job before it starts, so it is resumed if the processor
+// stops before the job is finished
+func (s *Server) startJob(job *redis.ProcessingMessage) {
+	if err := s.redis.HSet(jobsKey, job.JobID, job); err != nil {
+		log.Printf("Error saving job %s, it will not be resumed: %v", job.JobID, err)
+	}
+}
+
+// finishJob removes a job and its checkpoints once its results are published
+func (s *Server) finishJob(jobID string) {
+	if err := s.redis.Del(checkpointsKeyPrefix + jobID); err != nil {
+		log.Printf("Error removing checkpoints of job %s: %v", jobID, err)
+	}
+	if err := s.redis.HDel(jobsKey, jobID); err != nil {
+		log.Printf("Error removing job %s: %v", jobID, err)
+	}
+}
+
+// saveCheckpoint returns the callback storing the checkpoints of a job
+func (s *Server) saveCheckpoint(jobID string) processor.CheckpointCallback {
+	return func(checkpoint processor.Checkpoint) {
+		field := fmt.Sprintf("%s#%d", checkpoint.FileName, checkpoint.Chunk)
+		if err := s.redis.HSet(checkpointsKeyPrefix+jobID, field, checkpoint); err != nil {
+			log.Printf("Error saving checkpoint of %s for job %s: %v", field, jobID, err)
+		}
+	}
+}
+
+// loadCheckpoints returns the checkpoints saved by a job
+func (s *Server) loadCheckpoints(jobID string) ([]processor.Checkpoint, error) {
+	fields, err := s.redis.HGetAll(checkpointsKeyPrefix + jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]processor.Checkpoint, 0, len(fields))
+	for field, value := range fields {
+		var checkpoint processor.Checkpoint
+		if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %s: %v", field, err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+// resumeJobs runs the jobs left unfinished when the processor last stopped,
+// continuing from their checkpoints
+func (s *Server) resumeJobs() {
+	jobs, err := s.redis.HGetAll(jobsKey)
+	if err != nil {
+		log.Printf("Error loading unfinished jobs: %v", err)
+		return
+	}
+
+	for jobID, payload := range jobs {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		var job redis.ProcessingMessage
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			log.Printf("Dropping invalid job %s: %v", jobID, err)
+			s.finishJob(jobID)
+			continue
+		}
+
+		checkpoints, err := s.loadCheckpoints(jobID)
+		if err != nil {
+			// Without usable checkpoints the job starts over
+			log.Printf("Error loading checkpoints of job %s: %v", jobID, err)
+			s.redis.Del(checkpointsKeyPrefix + jobID)
+			checkpoints = nil
+		}
+
+		log.Printf("Resuming job %s for files: %v ~ %s", jobID, job.FileNames, job.ClientID)
+		if err := s.runJob(&job, checkpoints); err != nil {
+			log.Printf("Error resuming job %s: %v", jobID, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/processor"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/redis"
+)
+
+// listenDetect answers the format detection requests of the detect channel,
+// apart from the processing channel so the backend gets an answer while files
+// are being processed
+func (s *Server) listenDetect() {
+	pubsub, err := s.redis.Subscribe(s.config.DetectChannel)
+	if err != nil {
+		log.Printf("Failed to subscribe to Redis channel: %v", err)
+		return
+	}
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.ReceiveMessage(s.ctx)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error receiving detect message: %v", err)
+			continue
+		}
+
+		if err := s.handleDetect(msg.Payload); err != nil {
+			log.Printf("Error handling detect message: %v", err)
+		}
+	}
+}
+
+func (s *Server) handleDetect(payload string) error {
+	var detectMsg redis.DetectMessage
+	if err := json.Unmarshal([]byte(payload), &detectMsg); err != nil {
+		return fmt.Errorf("error unmarshaling message: %v", err)
+	}
+	if detectMsg.ReplyChannel == "" {
+		return fmt.Errorf("no reply channel to detect %q on", detectMsg.FileName)
+	}
+
+	var reply redis.DetectReply
+	if detectMsg.FileName == "" || filepath.Base(detectMsg.FileName) != detectMsg.FileName {
+		reply.Error = fmt.Sprintf("invalid file name %q", detectMsg.FileName)
+	} else {
+		detection, err := s.processor.DetectFormat(filepath.Join(s.config.UploadDir, detectMsg.FileName))
+		if err != nil {
+			reply.Error = err.Error()
+			reply.ErrorCategory = processor.Categorize(err)
+		} else {
+			reply.Detection = &detection
+			log.Printf("Detected format %s of file %s with confidence %.2f", detection.Format, detectMsg.FileName, detection.Confidence)
+		}
+	}
+
+	if err := s.redis.Publish(detectMsg.ReplyChannel, reply); err != nil {
+		return fmt.Errorf("error publishing detect reply: %v", err)
+	}
+	return nil
+}
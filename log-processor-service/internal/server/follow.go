@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/models"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/processor"
+	"github.com/ijasmoopan/intucloud-task/log-processor-service/internal/redis"
+)
+
+// followJob is a file being followed until it is cancelled
+type followJob struct {
+	cancel context.CancelFunc
+}
+
+// listenFollow starts and stops following files as requested on the follow
+// channel, apart from the processing channel so requests are handled while
+// files are being processed
+func (s *Server) listenFollow() {
+	pubsub, err := s.redis.Subscribe(s.config.FollowChannel)
+	if err != nil {
+		log.Printf("Failed to subscribe to Redis channel: %v", err)
+		return
+	}
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.ReceiveMessage(s.ctx)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error receiving follow message: %v", err)
+			continue
+		}
+
+		if err := s.handleFollow(msg.Payload); err != nil {
+			log.Printf("Error handling follow message: %v", err)
+		}
+	}
+}
+
+func (s *Server) handleFollow(payload string) error {
+	var followMsg redis.FollowMessage
+	if err := json.Unmarshal([]byte(payload), &followMsg); err != nil {
+		return fmt.Errorf("error unmarshaling message: %v", err)
+	}
+	if followMsg.FileName == "" || filepath.Base(followMsg.FileName) != followMsg.FileName {
+		return fmt.Errorf("invalid file name %q", followMsg.FileName)
+	}
+
+	switch followMsg.Action {
+	case redis.FollowStart:
+		return s.startFollow(&followMsg)
+	case redis.FollowStop:
+		return s.stopFollow(followMsg.FileName)
+	default:
+		return fmt.Errorf("unknown follow action %q", followMsg.Action)
+	}
+}
+
+// startFollow follows a file in the background, publishing its results for
+// the client that asked for it
+func (s *Server) startFollow(followMsg *redis.FollowMessage) error {
+	s.followsMu.Lock()
+	defer s.followsMu.Unlock()
+
+	fileName := followMsg.FileName
+	if _, ok := s.follows[fileName]; ok {
+		return fmt.Errorf("already following %s", fileName)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	job := &followJob{cancel: cancel}
+	s.follows[fileName] = job
+	log.Printf("Following file %s ~ %s", fileName, followMsg.ClientID)
+
+	publish := func(result models.Result) {
+		result.ClientID = followMsg.ClientID
+		if err := s.redis.Publish(s.config.ResultChannel, result); err != nil {
+			log.Printf("Error publishing result message: %v", err)
+		}
+	}
+
+	s.followsWg.Add(1)
+	go func() {
+		defer s.followsWg.Done()
+		defer func() {
+			cancel()
+			s.followsMu.Lock()
+			if s.follows[fileName] == job {
+				delete(s.follows, fileName)
+			}
+			s.followsMu.Unlock()
+		}()
+
+		filePath := filepath.Join(s.config.UploadDir, fileName)
+		err := s.processor.Follow(ctx, filePath, followMsg.FromEnd, func(delta, cumulative models.Result) {
+			publish(delta)
+			publish(cumulative)
+		})
+		if err != nil {
+			log.Printf("Error following file %s: %v", fileName, err)
+			publish(models.NewFailedResult(fileName, err, processor.Categorize(err)))
+		}
+	}()
+	return nil
+}
+
+// stopFollow stops following a file, which publishes its final results
+func (s *Server) stopFollow(fileName string) error {
+	s.followsMu.Lock()
+	defer s.followsMu.Unlock()
+
+	job, ok := s.follows[fileName]
+	if !ok {
+		return fmt.Errorf("not following %s", fileName)
+	}
+	job.cancel()
+	delete(s.follows, fileName)
+	log.Printf("Stopped following file %s", fileName)
+	return nil
+}
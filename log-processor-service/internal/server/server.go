@@ -3,10 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -22,6 +24,14 @@ type Server struct {
 	processor *processor.Processor
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// follows are the files being followed, by file name
+	follows   map[string]*followJob
+	followsMu sync.Mutex
+	followsWg sync.WaitGroup
+
+	// resumeWg waits for the jobs resumed on start
+	resumeWg sync.WaitGroup
 }
 
 func NewServer(cfg *config.Config) (*Server, error) {
@@ -31,15 +41,23 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
 	}
 
+	// Initialize the log processor
+	proc, err := processor.NewProcessor(cfg)
+	if err != nil {
+		redisClient.Close()
+		return nil, fmt.Errorf("failed to create processor: %v", err)
+	}
+
 	// Create a context that can be cancelled
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Server{
 		config:    cfg,
 		redis:     redisClient,
-		processor: processor.NewProcessor(cfg.NumWorkers, cfg.UploadDir),
+		processor: proc,
 		ctx:       ctx,
 		cancel:    cancel,
+		follows:   make(map[string]*followJob),
 	}, nil
 }
 
@@ -62,6 +80,16 @@ func (s *Server) Start() error {
 		s.cancel()
 	}()
 
+	go s.listenFollow()
+	go s.listenDetect()
+
+	// Finish the jobs interrupted by the last shutdown while taking new ones
+	s.resumeWg.Add(1)
+	go func() {
+		defer s.resumeWg.Done()
+		s.resumeJobs()
+	}()
+
 	log.Println("Log processor started. Waiting for messages...")
 
 	// Process messages from Redis
@@ -69,6 +97,10 @@ func (s *Server) Start() error {
 		select {
 		case <-s.ctx.Done():
 			log.Println("Shutting down...")
+			// Followed files and resumed jobs publish their final results
+			// before exiting
+			s.followsWg.Wait()
+			s.resumeWg.Wait()
 			return nil
 		default:
 			msg, err := pubsub.ReceiveMessage(s.ctx)
@@ -90,7 +122,23 @@ func (s *Server) handleMessage(msg *redis.Message) error {
 		return fmt.Errorf("error unmarshaling message: %v", err)
 	}
 
-	log.Printf("Received processing request for files: %v ~ %s", processingMsg.FileNames, processingMsg.ClientID)
+	if processingMsg.JobID == "" {
+		processingMsg.JobID = newJobID()
+	}
+
+	log.Printf("Received processing request %s for files: %v ~ %s", processingMsg.JobID, processingMsg.FileNames, processingMsg.ClientID)
+
+	s.startJob(&processingMsg)
+	return s.runJob(&processingMsg, nil)
+}
+
+// runJob processes the files of a job, continuing from the checkpoints of an
+// earlier run if any, and publishes the results of the files followed by the
+// summary of the job. Jobs cut short by a shutdown are kept to be resumed on
+// restart.
+func (s *Server) runJob(processingMsg *redis.ProcessingMessage, checkpoints []processor.Checkpoint) error {
+	summary := models.NewJobSummary(processingMsg.JobID, processingMsg.ClientID, time.Now())
+	summary.Resumed = checkpoints != nil
 
 	// Create progress callback function
 	progressCb := func(fileName string, progress int, status string, err error) {
@@ -113,33 +161,62 @@ func (s *Server) handleMessage(msg *redis.Message) error {
 			processingMsg.ClientID, fileName, progress, status)
 	}
 
-	// Process the files with progress updates
-	results, err := s.processor.ProcessFiles(processingMsg.FileNames, progressCb)
-	if err != nil {
+	opts := processor.JobOptions{Format: processingMsg.Format, Index: processingMsg.Index}
+	if processingMsg.Columns != nil {
+		opts.Columns = *processingMsg.Columns
+	}
+	if processingMsg.Filters != nil {
+		opts.Filters = *processingMsg.Filters
+	}
+
+	// Process the files with progress updates, stopping early on shutdown.
+	// Results of a cancelled run are still published, marked as partial.
+	outcomes, err := s.processor.ProcessFiles(s.ctx, processingMsg.FileNames, opts, checkpoints, progressCb, s.saveCheckpoint(processingMsg.JobID))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		summary.Error = err.Error()
+		s.publishSummary(summary, false)
 		return fmt.Errorf("error processing files: %v", err)
 	}
 
-	// Calculate totals
-	totalResult := models.Result{FilePath: "Total"}
-	for _, result := range results {
-		resultMsg := models.Result{
-			ClientID:   processingMsg.ClientID,
-			FilePath:   result.FilePath,
-			ErrorCount: result.ErrorCount,
-			WarnCount:  result.WarnCount,
+	for _, outcome := range outcomes {
+		summary.AddRestored(outcome.RestoredLines, outcome.RestoredBytes)
+		results := outcome.Results
+		if outcome.Failed() {
+			// Publish failures too, so the backend stores the file as failed
+			results = []models.Result{models.NewFailedResult(outcome.FileName, outcome.Err, outcome.Category)}
 		}
-		if err := s.redis.Publish(s.config.ResultChannel, resultMsg); err != nil {
-			log.Printf("Error publishing result message: %v", err)
+
+		for _, result := range results {
+			result.ClientID = processingMsg.ClientID
+			result.JobID = processingMsg.JobID
+			if err := s.redis.Publish(s.config.ResultChannel, result); err != nil {
+				log.Printf("Error publishing result message: %v", err)
+			}
+			// Archive members are already summed up in the total of their archive
+			if result.Member == "" {
+				summary.AddFile(outcome.FileName, result)
+			}
 		}
-		totalResult.Add(result)
 	}
 
-	fmt.Printf("Total -> Error Count: %d, Warn Count: %d\n",
-		totalResult.ErrorCount, totalResult.WarnCount)
+	s.publishSummary(summary, err != nil)
 
+	if err != nil {
+		return fmt.Errorf("processing cancelled, job %s resumes on restart: %v", processingMsg.JobID, err)
+	}
+	s.finishJob(processingMsg.JobID)
 	return nil
 }
 
+// publishSummary finishes the summary of a job and publishes it
+func (s *Server) publishSummary(summary *models.JobSummary, cancelled bool) {
+	summary.Finish(time.Now(), cancelled)
+	if err := s.redis.Publish(s.config.JobChannel, summary); err != nil {
+		log.Printf("Error publishing job summary: %v", err)
+	}
+	fmt.Println(summary.String())
+}
+
 func (s *Server) Stop() {
 	s.cancel()
 	s.redis.Close()